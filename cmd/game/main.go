@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"log/slog"
+	"math"
 	"math/rand"
 	"os"
 
@@ -21,6 +22,13 @@ const (
 	screenWidth        = 1280
 	screenHeight       = 720
 	gridSize     int64 = 5
+	maxHeight          = 2.0
+)
+
+// Factions on the demo board.
+const (
+	playerFaction hex.Faction = iota
+	enemyFaction
 )
 
 var (
@@ -45,24 +53,85 @@ func init() {
 	hexImage = ebitenImage
 }
 
+// tile is what the board stores for each hex.
+type tile struct {
+	color  color.Color
+	height float64
+}
+
 type Game struct {
 	updateCount int
 	bgColor     color.Color
 	debug       bool
 
+	board                *hex.Map[tile]
+	pathfinder           *hex.Pathfinder
 	layout               *hex.Layout
+	camera               *hex.Camera
+	lastMouseX           int
+	lastMouseY           int
 	hoveredQ, hoveredR   int64
 	selectedQ, selectedR int64
 
 	hasSelection               bool
 	pathFromSelectionToHovered []hex.Hex
 	visibleHexes               []hex.Hex
+
+	// threat is what the enemies project over the board, shown as the
+	// danger overlay when showDanger is set.
+	threat     *hex.InfluenceMap
+	peakThreat float64
+	showDanger bool
 }
 
 func NewGame() *Game {
+	layout := hex.NewLayout()
+	camera := hex.NewCamera(layout, screenWidth, screenHeight)
+
+	board := hex.NewParallelogramMap[tile](-gridSize, gridSize, -gridSize, gridSize)
+	board.Fill(func(h hex.Hex) tile {
+		t := tile{color: c.Color6}
+		if (h.Q+h.R)%2 == 0 {
+			t.color = c.Color5
+		}
+		// A couple of plateaus to show off elevation
+		switch {
+		case hex.HexDistance(h, hex.Hex{Q: 2, R: -3}) <= 1:
+			t.height = 1
+		case hex.HexDistance(h, hex.Hex{Q: -3, R: 2}) == 0:
+			t.height = maxHeight
+		case hex.HexDistance(h, hex.Hex{Q: -3, R: 2}) <= 1:
+			t.height = 1
+		}
+		return t
+	})
+	camera.SetBounds(board.Hexes())
+
+	pathfinder := hex.NewBoundedPathfinder(board.Hexes())
+	pathfinder.StraightLines = true
+
+	// A few enemies to show off the danger overlay. The tallest peak blocks
+	// their sight.
+	threat := hex.NewInfluenceMap(board.Hexes(), func(h hex.Hex) bool {
+		t, _ := board.Get(h)
+		return t.height >= maxHeight
+	})
+	threat.Add(enemyFaction, hex.Hex{Q: -4, R: 3}, 3, 3)
+	threat.Add(enemyFaction, hex.Hex{Q: 3, R: 1}, 2, 2)
+	threat.Add(enemyFaction, hex.Hex{Q: 4, R: -4}, 2, 4)
+	peakThreat := 0.0
+	for _, h := range board.Hexes() {
+		peakThreat = max(peakThreat, threat.Threat(h, playerFaction))
+	}
+
 	return &Game{
 		bgColor:                    color.RGBA{30, 30, 40, 255},
-		layout:                     hex.NewLayout(),
+		board:                      board,
+		pathfinder:                 pathfinder,
+		threat:                     threat,
+		peakThreat:                 peakThreat,
+		layout:                     layout,
+		camera:                     camera,
 		selectedQ:                  -999,
 		selectedR:                  -999,
 		pathFromSelectionToHovered: []hex.Hex{},
@@ -77,14 +146,17 @@ func (g *Game) Update() error {
 	}
 
 	mx, my := ebiten.CursorPosition()
-	g.hoveredQ, g.hoveredR = g.layout.PixelToHex(float64(mx), float64(my))
+	g.updateCamera(mx, my)
+	if h, ok := g.layout.PickHex(float64(mx), float64(my), g.board.Contains, g.height, maxHeight); ok {
+		g.hoveredQ, g.hoveredR = h.Q, h.R
+	} else {
+		g.hoveredQ, g.hoveredR = g.layout.PixelToHex(float64(mx), float64(my))
+	}
 	if g.hasSelection {
-		g.pathFromSelectionToHovered = hex.FindPath(
+		g.pathFromSelectionToHovered = g.pathfinder.FindPath(
 			hex.Hex{Q: g.selectedQ, R: g.selectedR},
 			hex.Hex{Q: g.hoveredQ, R: g.hoveredR},
-			func(h hex.Hex) bool {
-				return g.isValidHex(h.Q, h.R)
-			},
+			g.board.Contains,
 		)
 		g.visibleHexes = hex.GetVisibleHexes(
 			hex.Hex{Q: g.selectedQ, R: g.selectedR},
@@ -107,6 +179,9 @@ func (g *Game) Update() error {
 		if inpututil.IsKeyJustPressed(ebiten.KeyD) {
 			g.debug = !g.debug
 		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+			g.showDanger = !g.showDanger
+		}
 	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
@@ -117,27 +192,49 @@ func (g *Game) Update() error {
 		}
 	}
 
+	return nil
+}
+
+// updateCamera feeds mouse and keyboard input to the camera. It runs before
+// picking so the hovered hex matches what is drawn this frame.
+func (g *Game) updateCamera(mx, my int) {
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight) && !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+		g.camera.Pan(float64(mx-g.lastMouseX), float64(my-g.lastMouseY))
+	}
+	g.lastMouseX, g.lastMouseY = mx, my
+
+	if _, wheelY := ebiten.Wheel(); wheelY != 0 {
+		g.camera.ZoomAt(float64(mx), float64(my), math.Pow(hex.DefaultZoomStep, wheelY))
+	}
+
 	if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
-		g.layout.ZoomIn()
+		g.camera.ZoomIn()
 	} else if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
-		g.layout.ZoomOut()
+		g.camera.ZoomOut()
 	}
 
-	return nil
+	if ebiten.IsFocused() {
+		g.camera.EdgeScroll(float64(mx), float64(my))
+	}
+
+	g.camera.Update()
+}
+
+// height returns how far a hex is raised, with everything off the board at
+// ground level.
+func (g *Game) height(h hex.Hex) float64 {
+	t, _ := g.board.Get(h)
+	return t.height
 }
 
 func (g *Game) selectHex(q, r int64) {
-	if g.isValidHex(g.hoveredQ, g.hoveredR) {
+	if g.board.Contains(hex.Hex{Q: q, R: r}) {
 		g.selectedQ = g.hoveredQ
 		g.selectedR = g.hoveredR
 		g.hasSelection = true
 	}
 }
 
-func (g *Game) isValidHex(q, r int64) bool {
-	return q >= -gridSize && q <= gridSize && r >= -gridSize && r <= gridSize
-}
-
 func isAdjacent(q1, r1, q2, r2 int64) bool {
 	dq := q1 - q2
 	dr := r1 - r2
@@ -158,8 +255,34 @@ func hexListContains(hexes []hex.Hex, q, r int64) bool {
 	return false
 }
 
-func (g *Game) drawHex(screen *ebiten.Image, q, r int64) {
-	corners := g.layout.GetCorners(q, r)
+// drawWalls fills the sides a raised hex shows toward the viewer, shaded
+// darker than its top.
+func (g *Game) drawWalls(screen *ebiten.Image, h hex.Hex, baseColor color.Color) {
+	red, green, blue, alpha := baseColor.RGBA()
+	for _, wall := range g.layout.SideWalls(h, g.height) {
+		for i := range wall {
+			wall[i].SrcX, wall[i].SrcY = 0.5, 0.5
+			wall[i].ColorR = float32(red) / 0xffff * 0.6
+			wall[i].ColorG = float32(green) / 0xffff * 0.6
+			wall[i].ColorB = float32(blue) / 0xffff * 0.6
+			wall[i].ColorA = float32(alpha) / 0xffff
+		}
+		screen.DrawTriangles(wall, []uint16{0, 1, 2, 0, 2, 3}, emptyImage, nil)
+		for i := range wall {
+			next := (i + 1) % len(wall)
+			vector.StrokeLine(screen, wall[i].DstX, wall[i].DstY, wall[next].DstX, wall[next].DstY, 1, color.White, false)
+		}
+	}
+}
+
+func (g *Game) drawHex(screen *ebiten.Image, q, r int64, t tile) {
+	corners := g.layout.GetCornersAt(q, r, t.height)
+	baseColor := t.color
+	if g.showDanger {
+		if threat := g.threat.Threat(hex.Hex{Q: q, R: r}, playerFaction); threat > 0 {
+			baseColor = c.Danger(threat / g.peakThreat)
+		}
+	}
 
 	adjacentHexes := []hex.Hex{}
 	if g.hasSelection {
@@ -180,11 +303,7 @@ func (g *Game) drawHex(screen *ebiten.Image, q, r int64) {
 	} else if hexListContains(g.visibleHexes, q, r) {
 		hexColor = c.Color7
 	} else {
-		if (q+r)%2 == 0 {
-			hexColor = c.Color5
-		} else {
-			hexColor = c.Color6
-		}
+		hexColor = baseColor
 	}
 	var path vector.Path
 	path.MoveTo(corners[0].DstX, corners[0].DstY)
@@ -215,7 +334,7 @@ func (g *Game) drawHex(screen *ebiten.Image, q, r int64) {
 	}
 
 	if g.debug {
-		cx, cy := g.layout.HexToPixel(q, r)
+		cx, cy := g.layout.HexToPixelAt(q, r, t.height)
 		coordText := fmt.Sprintf("(%d,%d)", q, r)
 		// Approximate text positioning
 		ebitenutil.DebugPrintAt(screen, coordText, int(cx)-15, int(cy)-5)
@@ -225,10 +344,10 @@ func (g *Game) drawHex(screen *ebiten.Image, q, r int64) {
 func (g *Game) Draw(screen *ebiten.Image) {
 	screen.Fill(g.bgColor)
 
-	for q := -gridSize; q <= gridSize; q++ {
-		for r := -gridSize; r <= gridSize; r++ {
-			g.drawHex(screen, q, r)
-		}
+	for h := range g.layout.PaintersOrder(g.board.Hexes()) {
+		t, _ := g.board.Get(h)
+		g.drawWalls(screen, h, t.color)
+		g.drawHex(screen, h.Q, h.R, t)
 	}
 
 	msg := fmt.Sprintf("Milestone 2 - Hex Grid\nHovered Hex: (%d, %d)", g.hoveredQ, g.hoveredR)
@@ -238,6 +357,8 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	} else {
 		msg += "\nClick a hex to select it"
 	}
+	msg += "\nRight-drag to pan, mouse wheel to zoom"
+	msg += "\nPress ALT+T to toggle the danger overlay"
 	msg += "\nPress ALT+D to toggle debug info\nPress ALT+ENTER to toggle fullscreen\nPress ESC to quit"
 
 	ebitenutil.DebugPrintAt(screen, msg, 10, 10)
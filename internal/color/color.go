@@ -13,3 +13,21 @@ var (
 	Color6 = color.RGBA{160, 160, 100, 255} // Olive/tan
 	Color7 = color.RGBA{180, 140, 160, 255} // Light mauve
 )
+
+// Lerp blends a toward b: t of 0 gives a and 1 gives b.
+func Lerp(a, b color.RGBA, t float64) color.RGBA {
+	t = max(0, min(1, t))
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// Danger shades a threat level in [0, 1], from Color2 where it is safe
+// through Color3 to Color4 where it is deadliest.
+func Danger(t float64) color.RGBA {
+	if t < 0.5 {
+		return Lerp(Color2, Color3, t*2)
+	}
+	return Lerp(Color3, Color4, t*2-1)
+}
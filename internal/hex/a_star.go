@@ -6,8 +6,8 @@ import (
 )
 
 type Hex struct {
-	Q int64
-	R int64
+	Q int64 `json:"q"`
+	R int64 `json:"r"`
 }
 
 type PathNode struct {
@@ -72,43 +72,97 @@ func GetNeighbors(hex Hex) []Hex {
 	}
 }
 
+// CostFunc returns the cost of stepping from a hex onto an adjacent hex.
+// Costs must be positive for the search results to be meaningful.
+type CostFunc func(from, to Hex) int
+
+// HexCost adapts a per-hex entry cost (swamp, rubble, road) into a CostFunc.
+func HexCost(cost func(Hex) int) CostFunc {
+	return func(_, to Hex) int {
+		return cost(to)
+	}
+}
+
+func uniformCost(_, _ Hex) int {
+	return 1
+}
+
+// MaxPathNodes is how many hexes FindPath and its variants expand before
+// giving up, so a search for an unreachable goal ends even when isWalkable
+// does not bound the map. It covers a map of about 500 by 500 hexes; use
+// Search to set a different cap.
+const MaxPathNodes = 1 << 18
+
+// FindPath finds a shortest path from start to goal over walkable hexes, or
+// nil when there is none.
 func FindPath(start, goal Hex, isWalkable func(Hex) bool) []Hex {
+	path, _ := FindWeightedPath(start, goal, isWalkable, uniformCost, 1)
+	return path
+}
+
+// FindWeightedPath finds the cheapest path from start to goal and returns it
+// together with its total cost. minCost is the cheapest step cost cost can
+// return; the HexDistance heuristic is scaled by it so A* stays admissible.
+// A minCost of 0 turns the search into plain Dijkstra.
+// It returns nil and 0 when the goal cannot be reached, which it only knows
+// once every reachable hex is searched, or once MaxPathNodes hexes are.
+func FindWeightedPath(start, goal Hex, isWalkable func(Hex) bool, cost CostFunc, minCost int) ([]Hex, int) {
+	return findPath(start, goal, func(_, to Hex) bool { return isWalkable(to) }, cost, minCost)
+}
+
+// FindPathWithEdges is FindWeightedPath for maps with walls and closed doors
+// on hex borders: a step is also refused when isEdgeBlocked reports the edge
+// it crosses as blocked.
+func FindPathWithEdges(start, goal Hex, isWalkable func(Hex) bool, isEdgeBlocked func(Edge) bool, cost CostFunc, minCost int) ([]Hex, int) {
+	return findPath(start, goal, func(from, to Hex) bool {
+		edge, _ := EdgeBetween(from, to)
+		return isWalkable(to) && !isEdgeBlocked(edge)
+	}, cost, minCost)
+}
+
+// findPath is the A* search behind the FindPath variants. canStep decides
+// whether a move between two adjacent hexes is allowed.
+func findPath(start, goal Hex, canStep func(from, to Hex) bool, cost CostFunc, minCost int) ([]Hex, int) {
+	minCost = max(minCost, 0)
+
 	openSet := &PriorityQueue{}
 	closedSet := make(map[Hex]bool)
 	cameFrom := make(map[Hex]Hex)
-	gScore := make(map[Hex]int)
+	gScore := map[Hex]int{start: 0}
 	heap.Init(openSet)
 
 	heap.Push(openSet, &PathNode{hex: start, fScore: 0})
 
-	for openSet.Len() > 0 {
+	for openSet.Len() > 0 && len(closedSet) < MaxPathNodes {
 		current := heap.Pop(openSet).(*PathNode).hex
 
 		if current == goal {
-			return reconstructPath(cameFrom, current)
+			return reconstructPath(cameFrom, current), gScore[current]
 		}
 
+		// A hex can be queued more than once when a cheaper route to it is
+		// found later; only the first (cheapest) pop is expanded.
+		if closedSet[current] {
+			continue
+		}
 		closedSet[current] = true
 
 		for _, neighbor := range GetNeighbors(current) {
-			ifWalkable := isWalkable(neighbor)
-			_, inClosedSet := closedSet[neighbor]
-
-			if !ifWalkable || inClosedSet {
+			if closedSet[neighbor] || !canStep(current, neighbor) {
 				continue
 			}
 
-			tentativeGScore := gScore[current] + 1
+			tentativeGScore := gScore[current] + cost(current, neighbor)
 			tentative, exists := gScore[neighbor]
 			if !exists || tentativeGScore < tentative {
 				cameFrom[neighbor] = current
 				gScore[neighbor] = tentativeGScore
-				fScore := tentativeGScore + int(HexDistance(neighbor, goal))
+				fScore := tentativeGScore + int(HexDistance(neighbor, goal))*minCost
 				heap.Push(openSet, &PathNode{hex: neighbor, fScore: fScore})
 			}
 		}
 	}
-	return nil
+	return nil, 0
 }
 
 func reconstructPath(cameFrom map[Hex]Hex, current Hex) []Hex {
@@ -0,0 +1,162 @@
+package hex
+
+import "slices"
+
+// The analysis functions below work over a fixed set of hexes, such as
+// Map.Hexes, and treat everything outside it as wall.
+
+// ConnectedRegions splits the walkable hexes into groups that can reach one
+// another. Each region is in draw order, and regions are ordered by their
+// first hex, so the result is the same on every run.
+func ConnectedRegions(hexes []Hex, isWalkable func(Hex) bool) [][]Hex {
+	walkable := walkableSet(hexes, isWalkable)
+	seen := make(map[Hex]bool, len(walkable))
+	regions := [][]Hex{}
+	for _, start := range sortedHexes(walkable) {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		region := []Hex{start}
+		for i := 0; i < len(region); i++ {
+			for _, dir := range directions {
+				n := region[i].Add(dir)
+				if walkable[n] && !seen[n] {
+					seen[n] = true
+					region = append(region, n)
+				}
+			}
+		}
+		slices.SortFunc(region, compareDrawOrder)
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// Chokepoints returns the walkable hexes that, if blocked, would split their
+// region in two: doorways, bridges and one-hex corridors. AI can hold them
+// to cut a party off, and they are where a map's flow is easiest to block.
+func Chokepoints(hexes []Hex, isWalkable func(Hex) bool) []Hex {
+	walkable := walkableSet(hexes, isWalkable)
+	order := sortedHexes(walkable)
+
+	// Tarjan's articulation points, with an explicit stack so large maps
+	// cannot overflow the goroutine stack.
+	type frame struct {
+		h, parent Hex
+		next      int
+		children  int
+	}
+	discovered := make(map[Hex]int, len(order))
+	low := make(map[Hex]int, len(order))
+	cut := map[Hex]bool{}
+	for _, root := range order {
+		if _, ok := discovered[root]; ok {
+			continue
+		}
+		discovered[root], low[root] = len(discovered), len(discovered)
+		stack := []frame{{h: root, parent: root}}
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.next < len(directions) {
+				n := top.h.Add(directions[top.next])
+				top.next++
+				if !walkable[n] || n == top.parent {
+					continue
+				}
+				if d, ok := discovered[n]; ok {
+					low[top.h] = min(low[top.h], d)
+					continue
+				}
+				discovered[n], low[n] = len(discovered), len(discovered)
+				top.children++
+				stack = append(stack, frame{h: n, parent: top.h})
+				continue
+			}
+
+			done := *top
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				if done.children > 1 {
+					cut[done.h] = true
+				}
+				continue
+			}
+			parent := &stack[len(stack)-1]
+			low[parent.h] = min(low[parent.h], low[done.h])
+			if len(stack) > 1 && low[done.h] >= discovered[parent.h] {
+				cut[parent.h] = true
+			}
+		}
+	}
+
+	return sortedHexes(cut)
+}
+
+// WallDistance returns, for every hex, how many steps it is from the nearest
+// hex that is not walkable or not in the set. Walls are 0 and the hexes
+// along them 1. Units that want room to maneuver, or to keep their backs to
+// a wall, can read it directly.
+func WallDistance(hexes []Hex, isWalkable func(Hex) bool) *Map[int] {
+	distances := NewMap[int](hexes)
+	walkable := walkableSet(hexes, isWalkable)
+	fringe := []Hex{}
+	for h := range distances.All() {
+		if !walkable[h] {
+			continue
+		}
+		for _, dir := range directions {
+			if !walkable[h.Add(dir)] {
+				distances.Set(h, 1)
+				fringe = append(fringe, h)
+				break
+			}
+		}
+	}
+
+	for i := 0; i < len(fringe); i++ {
+		h := fringe[i]
+		d, _ := distances.Get(h)
+		for _, dir := range directions {
+			n := h.Add(dir)
+			if nd, ok := distances.Get(n); ok && walkable[n] && nd == 0 {
+				distances.Set(n, d+1)
+				fringe = append(fringe, n)
+			}
+		}
+	}
+	return distances
+}
+
+// OpenAreas returns the connected patches of ground at least clearance steps
+// from any wall, so that a hexagon of radius clearance-1 around each of
+// their hexes is walkable: room for a large boss or a whole party to stand.
+// A clearance below 1 is treated as 1, giving every walkable hex.
+func OpenAreas(hexes []Hex, isWalkable func(Hex) bool, clearance int) [][]Hex {
+	clearance = max(clearance, 1)
+	distances := WallDistance(hexes, isWalkable)
+	return ConnectedRegions(hexes, func(h Hex) bool {
+		d, _ := distances.Get(h)
+		return d >= clearance
+	})
+}
+
+func walkableSet(hexes []Hex, isWalkable func(Hex) bool) map[Hex]bool {
+	walkable := make(map[Hex]bool, len(hexes))
+	for _, h := range hexes {
+		if isWalkable(h) {
+			walkable[h] = true
+		}
+	}
+	return walkable
+}
+
+// sortedHexes returns the keys of a set in draw order.
+func sortedHexes(set map[Hex]bool) []Hex {
+	hexes := make([]Hex, 0, len(set))
+	for h := range set {
+		hexes = append(hexes, h)
+	}
+	slices.SortFunc(hexes, compareDrawOrder)
+	return hexes
+}
@@ -0,0 +1,162 @@
+// Package aoe builds area-of-effect templates for spells and boss attacks.
+// Every template is a plain []hex.Hex, so results can be combined, clipped
+// or highlighted like any other list of hexes.
+package aoe
+
+import (
+	"math"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+// angleEpsilon lets hexes lying exactly on a cone's edge count as inside.
+const angleEpsilon = 1e-9
+
+// coneHalfAngle is half of the 60° cone width.
+const coneHalfAngle = math.Pi / 6
+
+// Cone returns a 60° cone of the given length spreading from origin in one
+// of the six hex directions. Hexes whose centers lie on the cone's edges are
+// included; the origin itself is not.
+func Cone(origin hex.Hex, dir hex.Direction, length int64) []hex.Hex {
+	return coneAt(origin, float64(dir)*math.Pi/3, length)
+}
+
+// ConeToward returns a 60° cone from origin aimed straight at target, which
+// need not lie along a hex direction. Use Cone with hex.DirectionTowards to
+// snap the aim to the nearest direction instead.
+func ConeToward(origin, target hex.Hex, length int64) []hex.Hex {
+	if origin == target {
+		return []hex.Hex{}
+	}
+	return coneAt(origin, hex.Angle(origin, target), length)
+}
+
+func coneAt(origin hex.Hex, angle float64, length int64) []hex.Hex {
+	results := []hex.Hex{}
+	for r := int64(1); r <= length; r++ {
+		for _, h := range hex.Ring(origin, r) {
+			if angleBetween(hex.Angle(origin, h), angle) <= coneHalfAngle+angleEpsilon {
+				results = append(results, h)
+			}
+		}
+	}
+	return results
+}
+
+// angleBetween returns the absolute difference between two angles, in [0, π].
+func angleBetween(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 2*math.Pi)
+	if d > math.Pi {
+		d = 2*math.Pi - d
+	}
+	return d
+}
+
+// Line returns a straight line of hexes from one hex to another, width hexes
+// across. A width of 1 is exactly hex.HexLine; wider lines add every hex
+// whose center lies within (width-1)/2 hex spacings of the segment, with
+// square ends.
+func Line(from, to hex.Hex, width int64) []hex.Hex {
+	line := hex.HexLine(from, to)
+	if width <= 1 || from == to {
+		return line
+	}
+
+	seen := make(map[hex.Hex]bool, len(line))
+	results := make([]hex.Hex, 0, len(line)*int(width))
+	for _, h := range line {
+		seen[h] = true
+		results = append(results, h)
+	}
+
+	fx, fy := center(from)
+	tx, ty := center(to)
+	dx, dy := tx-fx, ty-fy
+	lengthSq := dx*dx + dy*dy
+	// Neighboring hex centers are 1 apart in the center() plane.
+	halfWidth := float64(width-1)/2 + angleEpsilon
+
+	for _, h := range line {
+		for _, candidate := range hex.Range(h, width) {
+			if seen[candidate] {
+				continue
+			}
+			cx, cy := center(candidate)
+			t := ((cx-fx)*dx + (cy-fy)*dy) / lengthSq
+			if t < 0 || t > 1 {
+				continue
+			}
+			px, py := fx+t*dx, fy+t*dy
+			if math.Hypot(cx-px, cy-py) <= halfWidth {
+				seen[candidate] = true
+				results = append(results, candidate)
+			}
+		}
+	}
+	return results
+}
+
+// center returns a hex center on a plane where neighboring centers are one
+// unit apart, matching hex.Angle.
+func center(h hex.Hex) (float64, float64) {
+	q, r := float64(h.Q), float64(h.R)
+	return q + r/2, -r * math.Sqrt(3) / 2
+}
+
+// Burst returns every hex within radius of origin, origin included.
+func Burst(origin hex.Hex, radius int64) []hex.Hex {
+	return hex.Range(origin, radius)
+}
+
+// RingWithHole returns the hexes between inner and outer steps from origin,
+// inclusive, leaving the middle untouched.
+func RingWithHole(origin hex.Hex, inner, outer int64) []hex.Hex {
+	results := []hex.Hex{}
+	for r := max(inner, 0); r <= outer; r++ {
+		results = append(results, hex.Ring(origin, r)...)
+	}
+	return results
+}
+
+// Wall returns the straight line of hexes through origin running
+// perpendicular to facing, reaching halfLength steps out on each side.
+func Wall(origin hex.Hex, facing hex.Direction, halfLength int64) []hex.Hex {
+	// The sum of the two directions 60° and 120° from facing points 90°
+	// away from it, two hex steps long.
+	across := facing.Rotate(1).Offset().Add(facing.Rotate(2).Offset()).Scale(halfLength)
+	start, end := origin.Sub(across), origin.Add(across)
+
+	results := []hex.Hex{}
+	for _, h := range hex.HexLine(start, end) {
+		if hex.HexDistance(origin, h) <= halfLength {
+			results = append(results, h)
+		}
+	}
+	return results
+}
+
+// ClipToLineOfSight keeps only the hexes the caster can see.
+func ClipToLineOfSight(caster hex.Hex, hexes []hex.Hex, isBlocking func(hex.Hex) bool) []hex.Hex {
+	results := make([]hex.Hex, 0, len(hexes))
+	for _, h := range hexes {
+		if hex.HasLineOfSight(caster, h, isBlocking) {
+			results = append(results, h)
+		}
+	}
+	return results
+}
+
+// Stamp places a template authored around (0,0) and facing Direction 0 at
+// origin, turned to face facing. Boss patterns and prefabs can be drawn once
+// and stamped in any of the six orientations.
+func Stamp(template []hex.Hex, origin hex.Hex, facing hex.Direction) []hex.Hex {
+	return hex.Rotation(hex.Hex{}, int(facing)).Then(hex.Translation(origin)).Apply(template)
+}
+
+// Mirror flips a template authored around (0,0) and facing Direction 0 left
+// to right, so its facing stays the same. Stamp the result as usual.
+func Mirror(template []hex.Hex) []hex.Hex {
+	// Reflecting across AxisR reverses Direction 0, so turn it back round.
+	return hex.Reflection(hex.Hex{}, hex.AxisR).Then(hex.Rotation(hex.Hex{}, 3)).Apply(template)
+}
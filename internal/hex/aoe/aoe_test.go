@@ -0,0 +1,190 @@
+package aoe
+
+import (
+	"math"
+	"testing"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+func toSet(hexes []hex.Hex) map[hex.Hex]bool {
+	set := make(map[hex.Hex]bool, len(hexes))
+	for _, h := range hexes {
+		set[h] = true
+	}
+	return set
+}
+
+// TestConeDirections verifies a cone has the same shape in all six directions
+func TestConeDirections(t *testing.T) {
+	origin := hex.Hex{Q: 1, R: -2}
+	for dir := hex.Direction(0); dir < 6; dir++ {
+		cone := Cone(origin, dir, 4)
+		if len(cone) != 12 || len(toSet(cone)) != 12 {
+			t.Errorf("direction %d: cone has %d hexes, want 12", dir, len(cone))
+		}
+		for _, h := range cone {
+			if d := hex.HexDistance(origin, h); d < 1 || d > 4 {
+				t.Errorf("direction %d: %v is %d away", dir, h, d)
+			}
+		}
+
+		// The hexes straight ahead are always hit
+		ahead := origin
+		for i := 0; i < 4; i++ {
+			ahead = hex.Neighbor(ahead, dir)
+			if !toSet(cone)[ahead] {
+				t.Errorf("direction %d: cone misses %v straight ahead", dir, ahead)
+			}
+		}
+	}
+}
+
+// TestConeToward verifies aiming at a target along a direction matches the
+// directional cone, and aiming between directions gives a wedge
+func TestConeToward(t *testing.T) {
+	origin := hex.Hex{}
+	target := hex.Hex{Q: 0, R: -5}
+
+	got := toSet(ConeToward(origin, target, 3))
+	want := Cone(origin, hex.DirectionTowards(origin, target), 3)
+	if len(got) != len(want) {
+		t.Errorf("ConeToward has %d hexes, Cone has %d", len(got), len(want))
+	}
+	for _, h := range want {
+		if !got[h] {
+			t.Errorf("ConeToward is missing %v", h)
+		}
+	}
+
+	// (2,-1) lies exactly between directions 0 and 1, so the cone is the
+	// wedge between them with k+1 hexes in ring k
+	if wedge := ConeToward(origin, hex.Hex{Q: 2, R: -1}, 3); len(wedge) != 9 {
+		t.Errorf("wedge cone has %d hexes, want 9", len(wedge))
+	}
+
+	if len(ConeToward(origin, origin, 3)) != 0 {
+		t.Error("a cone aimed at the caster should be empty")
+	}
+}
+
+// TestLineWidth verifies wide lines contain the thin line and stay near it
+func TestLineWidth(t *testing.T) {
+	from, to := hex.Hex{}, hex.Hex{Q: 4, R: -1}
+
+	thin := Line(from, to, 1)
+	if len(thin) != len(hex.HexLine(from, to)) {
+		t.Errorf("width 1 line has %d hexes, want %d", len(thin), len(hex.HexLine(from, to)))
+	}
+
+	wide := toSet(Line(from, to, 3))
+	for _, h := range thin {
+		if !wide[h] {
+			t.Errorf("wide line is missing %v from the thin line", h)
+		}
+	}
+	if len(wide) <= len(thin) {
+		t.Errorf("width 3 line has only %d hexes", len(wide))
+	}
+	for h := range wide {
+		near := false
+		for _, l := range thin {
+			if hex.HexDistance(h, l) <= 1 {
+				near = true
+			}
+		}
+		if !near {
+			t.Errorf("%v is more than one hex from the line", h)
+		}
+	}
+
+	// Along a hex direction a width 3 line is three full rows, squared off
+	// at the ends
+	if got := len(Line(hex.Hex{}, hex.Hex{Q: 4, R: 0}, 3)); got != 13 {
+		t.Errorf("straight width 3 line has %d hexes, want 13", got)
+	}
+}
+
+// TestBurstAndRingWithHole verifies the filled and hollow circular templates
+func TestBurstAndRingWithHole(t *testing.T) {
+	origin := hex.Hex{Q: 3, R: 3}
+	if got := len(Burst(origin, 2)); got != 19 {
+		t.Errorf("Burst(2) has %d hexes, want 19", got)
+	}
+
+	donut := RingWithHole(origin, 2, 3)
+	if len(donut) != 30 {
+		t.Errorf("RingWithHole(2,3) has %d hexes, want 30", len(donut))
+	}
+	for _, h := range donut {
+		if d := hex.HexDistance(origin, h); d < 2 || d > 3 {
+			t.Errorf("RingWithHole contains %v at distance %d", h, d)
+		}
+	}
+}
+
+// TestWall verifies walls run perpendicular to the facing direction
+func TestWall(t *testing.T) {
+	origin := hex.Hex{Q: -1, R: 2}
+	for facing := hex.Direction(0); facing < 6; facing++ {
+		wall := Wall(origin, facing, 3)
+		if len(wall) != 7 {
+			t.Errorf("facing %d: wall has %d hexes, want 7", facing, len(wall))
+		}
+
+		fx, fy := center(hex.Neighbor(hex.Hex{}, facing))
+		ox, oy := center(origin)
+		for _, h := range wall {
+			// Distance along the facing direction stays within half a hex
+			hx, hy := center(h)
+			if along := (hx-ox)*fx + (hy-oy)*fy; math.Abs(along) > 0.5+1e-9 {
+				t.Errorf("facing %d: %v sticks out %f along the facing", facing, h, along)
+			}
+		}
+	}
+}
+
+// TestClipToLineOfSight verifies walls shield hexes behind them
+func TestClipToLineOfSight(t *testing.T) {
+	caster := hex.Hex{}
+	wall := hex.Hex{Q: 1, R: 0}
+	clipped := toSet(ClipToLineOfSight(caster, Cone(caster, 0, 3), func(h hex.Hex) bool { return h == wall }))
+
+	if !clipped[wall] {
+		t.Error("the wall itself should still be hit")
+	}
+	if clipped[hex.Hex{Q: 2, R: 0}] || clipped[hex.Hex{Q: 3, R: 0}] {
+		t.Error("hexes behind the wall should be clipped")
+	}
+	if !clipped[hex.Hex{Q: 2, R: -1}] {
+		t.Error("hexes beside the wall should stay in the template")
+	}
+}
+
+// TestStampAndMirror verifies a template authored facing Direction 0 can be
+// placed in every orientation, and mirrored without changing its facing
+func TestStampAndMirror(t *testing.T) {
+	origin := hex.Hex{Q: 4, R: -1}
+	template := Cone(hex.Hex{}, 0, 3)
+	for d := hex.Direction(0); d < 6; d++ {
+		got, want := toSet(Stamp(template, origin, d)), toSet(Cone(origin, d, 3))
+		if len(got) != len(want) {
+			t.Fatalf("direction %d: stamped %d hexes, want %d", d, len(got), len(want))
+		}
+		for h := range want {
+			if !got[h] {
+				t.Errorf("direction %d: stamped cone is missing %v", d, h)
+			}
+		}
+	}
+
+	// A hook curling toward Direction 1 curls toward Direction 5 mirrored
+	hook := []hex.Hex{{Q: 1, R: 0}, {Q: 2, R: 0}, {Q: 3, R: -1}}
+	mirrored := Mirror(hook)
+	want := []hex.Hex{{Q: 1, R: 0}, {Q: 2, R: 0}, {Q: 2, R: 1}}
+	for i := range want {
+		if mirrored[i] != want[i] {
+			t.Fatalf("mirrored = %v, want %v", mirrored, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package hex
+
+import (
+	"math"
+
+	hx "github.com/gojuno/go.hexgrid"
+)
+
+const (
+	DefaultMinHexSize   = 10.0
+	DefaultMaxHexSize   = 120.0
+	DefaultSmoothing    = 0.2
+	DefaultEdgeMargin   = 16.0
+	DefaultEdgeSpeed    = 8.0
+	DefaultZoomStep     = 1.1
+	cameraSnapThreshold = 0.01
+)
+
+// Camera pans and zooms a Layout. It knows nothing about input devices: the
+// game feeds it drags, wheel ticks and the cursor position, then calls
+// Update once per tick to ease toward the requested view. The layout always
+// reflects the camera's current state, so PixelToHex keeps picking the hex
+// that is drawn under the cursor while the camera moves.
+type Camera struct {
+	layout        *Layout
+	screenW       float64
+	screenH       float64
+	originX       float64
+	originY       float64
+	size          float64
+	targetOriginX float64
+	targetOriginY float64
+	targetSize    float64
+
+	// anchored is set while a zoom is easing in, so the world point under
+	// the cursor stays put on every intermediate frame.
+	anchored bool
+	anchorX  float64
+	anchorY  float64
+	worldX   float64
+	worldY   float64
+
+	hasBounds bool
+	minX      float64
+	minY      float64
+	maxX      float64
+	maxY      float64
+
+	MinSize float64
+	MaxSize float64
+	// Smoothing is the fraction of the remaining distance covered per
+	// Update; 1 snaps straight to the target.
+	Smoothing  float64
+	EdgeMargin float64
+	EdgeSpeed  float64
+}
+
+func NewCamera(layout *Layout, screenWidth, screenHeight float64) *Camera {
+	x, y := layout.Origin()
+	size := layout.Size()
+	return &Camera{
+		layout:        layout,
+		screenW:       screenWidth,
+		screenH:       screenHeight,
+		originX:       x,
+		originY:       y,
+		size:          size,
+		targetOriginX: x,
+		targetOriginY: y,
+		targetSize:    size,
+		MinSize:       DefaultMinHexSize,
+		MaxSize:       DefaultMaxHexSize,
+		Smoothing:     DefaultSmoothing,
+		EdgeMargin:    DefaultEdgeMargin,
+		EdgeSpeed:     DefaultEdgeSpeed,
+	}
+}
+
+// SetBounds limits panning so the given hexes stay on screen. A map smaller
+// than the screen is kept centered instead.
+func (c *Camera) SetBounds(hexes []Hex) {
+	if len(hexes) == 0 {
+		c.hasBounds = false
+		return
+	}
+
+	// Measure the map at size 1 with the origin at 0, which is exactly the
+	// per-unit-of-size offset the camera works in.
+	config := c.layout.Config()
+	config.Size, config.OriginX, config.OriginY = 1, 0, 0
+	unit := NewLayoutWithConfig(config)
+
+	c.minX, c.minY = math.Inf(1), math.Inf(1)
+	c.maxX, c.maxY = math.Inf(-1), math.Inf(-1)
+	for _, h := range hexes {
+		for _, corner := range unit.hexgrid.HexCorners(hx.MakeHex(h.Q, h.R)) {
+			c.minX, c.maxX = min(c.minX, corner.X()), max(c.maxX, corner.X())
+			c.minY, c.maxY = min(c.minY, corner.Y()), max(c.maxY, corner.Y())
+		}
+	}
+	c.hasBounds = true
+	c.clampTarget()
+	c.originX, c.originY = c.clamp(c.originX, c.originY, c.size)
+	c.apply()
+}
+
+// Pan moves the view by a screen-space delta straight away, without easing,
+// so dragging feels attached to the cursor.
+func (c *Camera) Pan(dx, dy float64) {
+	c.originX += dx
+	c.originY += dy
+	c.targetOriginX += dx
+	c.targetOriginY += dy
+	c.anchorX += dx
+	c.anchorY += dy
+	c.originX, c.originY = c.clamp(c.originX, c.originY, c.size)
+	c.clampTarget()
+	c.apply()
+}
+
+// ZoomAt scales the view by factor, keeping the point under the given
+// screen position fixed. The change eases in over the following Updates.
+func (c *Camera) ZoomAt(screenX, screenY, factor float64) {
+	c.worldX = (screenX - c.originX) / c.size
+	c.worldY = (screenY - c.originY) / c.size
+	c.anchorX, c.anchorY = screenX, screenY
+	c.anchored = true
+
+	c.targetSize = math.Max(c.MinSize, math.Min(c.MaxSize, c.targetSize*factor))
+	c.targetOriginX = screenX - c.worldX*c.targetSize
+	c.targetOriginY = screenY - c.worldY*c.targetSize
+	c.clampTarget()
+}
+
+// ZoomIn and ZoomOut zoom one step around the center of the screen.
+func (c *Camera) ZoomIn() {
+	c.ZoomAt(c.screenW/2, c.screenH/2, DefaultZoomStep)
+}
+
+func (c *Camera) ZoomOut() {
+	c.ZoomAt(c.screenW/2, c.screenH/2, 1/DefaultZoomStep)
+}
+
+// EdgeScroll pans toward any screen edge the cursor is within EdgeMargin of.
+func (c *Camera) EdgeScroll(cursorX, cursorY float64) {
+	var dx, dy float64
+	switch {
+	case cursorX < c.EdgeMargin:
+		dx = c.EdgeSpeed
+	case cursorX > c.screenW-c.EdgeMargin:
+		dx = -c.EdgeSpeed
+	}
+	switch {
+	case cursorY < c.EdgeMargin:
+		dy = c.EdgeSpeed
+	case cursorY > c.screenH-c.EdgeMargin:
+		dy = -c.EdgeSpeed
+	}
+	if dx == 0 && dy == 0 {
+		return
+	}
+
+	c.anchored = false
+	c.targetOriginX += dx
+	c.targetOriginY += dy
+	c.clampTarget()
+}
+
+// Update eases the view toward its target and pushes the result to the
+// layout.
+func (c *Camera) Update() {
+	c.size = approach(c.size, c.targetSize, c.Smoothing)
+	if c.anchored {
+		c.originX = c.anchorX - c.worldX*c.size
+		c.originY = c.anchorY - c.worldY*c.size
+		if c.size == c.targetSize {
+			c.anchored = false
+		}
+	} else {
+		c.originX = approach(c.originX, c.targetOriginX, c.Smoothing)
+		c.originY = approach(c.originY, c.targetOriginY, c.Smoothing)
+	}
+	c.originX, c.originY = c.clamp(c.originX, c.originY, c.size)
+	c.apply()
+}
+
+// Settled reports whether the camera has reached its target.
+func (c *Camera) Settled() bool {
+	return c.size == c.targetSize && c.originX == c.targetOriginX && c.originY == c.targetOriginY
+}
+
+func (c *Camera) apply() {
+	config := c.layout.Config()
+	if config.OriginX == c.originX && config.OriginY == c.originY && config.Size == c.size {
+		return
+	}
+	config.OriginX, config.OriginY, config.Size = c.originX, c.originY, c.size
+	c.layout.SetConfig(config)
+}
+
+func (c *Camera) clampTarget() {
+	c.targetOriginX, c.targetOriginY = c.clamp(c.targetOriginX, c.targetOriginY, c.targetSize)
+}
+
+// clamp keeps the map covering the screen on each axis, or centers it on
+// axes where it is smaller than the screen.
+func (c *Camera) clamp(originX, originY, size float64) (float64, float64) {
+	if !c.hasBounds {
+		return originX, originY
+	}
+	return clampAxis(originX, c.minX*size, c.maxX*size, c.screenW),
+		clampAxis(originY, c.minY*size, c.maxY*size, c.screenH)
+}
+
+func clampAxis(origin, lo, hi, screen float64) float64 {
+	if hi-lo <= screen {
+		return (screen-(hi-lo))/2 - lo
+	}
+	return math.Max(screen-hi, math.Min(-lo, origin))
+}
+
+// approach moves current a fraction of the way to target, snapping once the
+// remaining distance is negligible.
+func approach(current, target, fraction float64) float64 {
+	next := current + (target-current)*fraction
+	if math.Abs(target-next) < cameraSnapThreshold {
+		return target
+	}
+	return next
+}
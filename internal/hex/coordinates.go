@@ -0,0 +1,103 @@
+package hex
+
+// OffsetSystem selects which of the four offset coordinate layouts a map
+// editor used. The q variants stack hexes in columns for flat-top maps, the
+// r variants in rows for pointy-top maps; odd or even says which columns or
+// rows are shoved half a hex down or right.
+type OffsetSystem int
+
+const (
+	OddQ OffsetSystem = iota
+	EvenQ
+	OddR
+	EvenR
+)
+
+// Orientation returns the layout the offset system is meant to be drawn with.
+func (s OffsetSystem) Orientation() Orientation {
+	if s == OddR || s == EvenR {
+		return Pointy
+	}
+	return Flat
+}
+
+// OffsetCoord is a column and row in one of the offset systems.
+type OffsetCoord struct {
+	Col, Row int64
+}
+
+// ToOffset converts an axial hex to offset coordinates.
+func ToOffset(h Hex, system OffsetSystem) OffsetCoord {
+	switch system {
+	case OddQ:
+		return OffsetCoord{Col: h.Q, Row: h.R + (h.Q-h.Q&1)/2}
+	case EvenQ:
+		return OffsetCoord{Col: h.Q, Row: h.R + (h.Q+h.Q&1)/2}
+	case OddR:
+		return OffsetCoord{Col: h.Q + (h.R-h.R&1)/2, Row: h.R}
+	default:
+		return OffsetCoord{Col: h.Q + (h.R+h.R&1)/2, Row: h.R}
+	}
+}
+
+// FromOffset converts offset coordinates back to an axial hex. Every offset
+// coordinate names exactly one hex, so the round trip is lossless.
+func FromOffset(c OffsetCoord, system OffsetSystem) Hex {
+	switch system {
+	case OddQ:
+		return Hex{Q: c.Col, R: c.Row - (c.Col-c.Col&1)/2}
+	case EvenQ:
+		return Hex{Q: c.Col, R: c.Row - (c.Col+c.Col&1)/2}
+	case OddR:
+		return Hex{Q: c.Col - (c.Row-c.Row&1)/2, R: c.Row}
+	default:
+		return Hex{Q: c.Col - (c.Row+c.Row&1)/2, R: c.Row}
+	}
+}
+
+// DoubledCoord is a hex in doubled coordinates: double-height for flat-top
+// layouts, where rows step by two down each column, and double-width for
+// pointy-top layouts, where columns step by two along each row. Col+Row is
+// always even.
+type DoubledCoord struct {
+	Col, Row int64
+}
+
+// ToDoubled converts an axial hex to the doubled coordinates that suit the
+// orientation.
+func ToDoubled(h Hex, orientation Orientation) DoubledCoord {
+	if orientation == Pointy {
+		return DoubledCoord{Col: 2*h.Q + h.R, Row: h.R}
+	}
+	return DoubledCoord{Col: h.Q, Row: 2*h.R + h.Q}
+}
+
+// FromDoubled converts doubled coordinates back to an axial hex. It returns
+// false when Col+Row is odd, since no hex sits there.
+func FromDoubled(c DoubledCoord, orientation Orientation) (Hex, bool) {
+	if (c.Col+c.Row)&1 != 0 {
+		return Hex{}, false
+	}
+	if orientation == Pointy {
+		return Hex{Q: (c.Col - c.Row) / 2, R: c.Row}, true
+	}
+	return Hex{Q: c.Col, R: (c.Row - c.Col) / 2}, true
+}
+
+// Cube is a hex in cube coordinates, where Q+R+S is always zero.
+type Cube struct {
+	Q, R, S int64
+}
+
+func ToCube(h Hex) Cube {
+	return Cube{Q: h.Q, R: h.R, S: -h.Q - h.R}
+}
+
+// FromCube drops the redundant S coordinate. It returns false when the
+// coordinates do not add up to zero.
+func FromCube(c Cube) (Hex, bool) {
+	if c.Q+c.R+c.S != 0 {
+		return Hex{}, false
+	}
+	return Hex{Q: c.Q, R: c.R}, true
+}
@@ -0,0 +1,109 @@
+package hex
+
+// Cover is how much of a target is hidden from an attacker, following the
+// D&D 5e cover rules.
+type Cover int
+
+const (
+	CoverNone Cover = iota
+	CoverHalf
+	CoverThreeQuarters
+	CoverFull
+)
+
+// UnitHeight is how tall a standing creature is, in the same units as the
+// height callbacks below. Eyes are at the top.
+const UnitHeight = 1.0
+
+// coverSamples are the points on the target's body, as fractions of
+// UnitHeight above its feet, that sight lines are cast to.
+var coverSamples = [4]float64{0.125, 0.375, 0.625, 0.875}
+
+func (c Cover) String() string {
+	switch c {
+	case CoverNone:
+		return "none"
+	case CoverHalf:
+		return "half"
+	case CoverThreeQuarters:
+		return "three-quarters"
+	default:
+		return "full"
+	}
+}
+
+// ACBonus returns the armor class bonus the cover grants. Full cover returns
+// 0 because the target cannot be targeted at all; check Targetable first.
+func (c Cover) ACBonus() int {
+	switch c {
+	case CoverHalf:
+		return 2
+	case CoverThreeQuarters:
+		return 5
+	default:
+		return 0
+	}
+}
+
+func (c Cover) Targetable() bool {
+	return c != CoverFull
+}
+
+// CoverBetween works out how much cover a creature on to has from a creature
+// on from. height returns the top of whatever fills each hex: the ground for
+// open terrain, or the top of a wall, boulder or ledge. Creatures stand on
+// the height of their own hex, so a raised hex sees over lower obstacles and
+// tall obstacles shade more of the hexes behind them.
+//
+// Sight lines run from the attacker's eyes to four points spread up the
+// target's body along both nudged hex lines; the less obstructed of the two
+// decides the cover, so edge-grazing lines are treated as fairly as by
+// HasLineOfSight.
+func CoverBetween(from, to Hex, height func(Hex) float64) Cover {
+	if from == to {
+		return CoverNone
+	}
+
+	best := CoverFull
+	for _, nudge := range []float64{LineEpsilon, -LineEpsilon} {
+		line := HexLineNudged(from, to, nudge)
+		best = min(best, coverAlong(line, height))
+	}
+	return best
+}
+
+// HasElevatedLineOfSight reports whether any part of a creature on to can be
+// seen from from.
+func HasElevatedLineOfSight(from, to Hex, height func(Hex) float64) bool {
+	return CoverBetween(from, to, height).Targetable()
+}
+
+func coverAlong(line []Hex, height func(Hex) float64) Cover {
+	from, to := line[0], line[len(line)-1]
+	eye := height(from) + UnitHeight
+	feet := height(to)
+	steps := float64(len(line) - 1)
+
+	blocked := 0
+	for _, sample := range coverSamples {
+		target := feet + sample*UnitHeight
+		for i := 1; i < len(line)-1; i++ {
+			t := float64(i) / steps
+			if height(line[i]) > eye+(target-eye)*t {
+				blocked++
+				break
+			}
+		}
+	}
+
+	switch {
+	case blocked == 0:
+		return CoverNone
+	case blocked <= len(coverSamples)/2:
+		return CoverHalf
+	case blocked < len(coverSamples):
+		return CoverThreeQuarters
+	default:
+		return CoverFull
+	}
+}
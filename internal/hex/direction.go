@@ -0,0 +1,103 @@
+package hex
+
+import "math"
+
+// Direction names one of the six neighbors of a hex. Directions run
+// counter-clockwise on screen, so opposite directions are three apart.
+type Direction int
+
+// directions holds the axial offset for each Direction, in the order a ring
+// walk turns through them.
+var directions = [6]Hex{
+	{1, 0}, {1, -1}, {0, -1}, {-1, 0}, {-1, 1}, {0, 1},
+}
+
+// Compass names for the directions of a flat-top layout, which has north and
+// south neighbors but none due east or west.
+const (
+	FlatSouthEast Direction = iota
+	FlatNorthEast
+	FlatNorth
+	FlatNorthWest
+	FlatSouthWest
+	FlatSouth
+)
+
+// Compass names for the directions of a pointy-top layout, which has east and
+// west neighbors but none due north or south.
+const (
+	PointyEast Direction = iota
+	PointyNorthEast
+	PointyNorthWest
+	PointyWest
+	PointySouthWest
+	PointySouthEast
+)
+
+var (
+	flatNames   = [6]string{"south-east", "north-east", "north", "north-west", "south-west", "south"}
+	pointyNames = [6]string{"east", "north-east", "north-west", "west", "south-west", "south-east"}
+)
+
+// Name returns the compass name of the direction as it appears on screen in
+// a layout with the given orientation.
+func (d Direction) Name(orientation Orientation) string {
+	if orientation == Pointy {
+		return pointyNames[d.normalize()]
+	}
+	return flatNames[d.normalize()]
+}
+
+// Offset returns the axial step for the direction.
+func (d Direction) Offset() Hex {
+	return directions[d.normalize()]
+}
+
+// Rotate turns the direction by steps multiples of 60°, counter-clockwise for
+// positive steps.
+func (d Direction) Rotate(steps int) Direction {
+	return (d + Direction(steps)).normalize()
+}
+
+func (d Direction) Opposite() Direction {
+	return d.Rotate(3)
+}
+
+func (d Direction) normalize() Direction {
+	return ((d % 6) + 6) % 6
+}
+
+// Neighbor returns the hex one step from h in direction d.
+func Neighbor(h Hex, d Direction) Hex {
+	offset := d.Offset()
+	return Hex{Q: h.Q + offset.Q, R: h.R + offset.R}
+}
+
+// Angle returns the angle in radians of the vector from one hex center to
+// another, measured counter-clockwise from Direction 0. Only differences
+// between angles are meaningful across layouts.
+func Angle(from, to Hex) float64 {
+	dq, dr := float64(to.Q-from.Q), float64(to.R-from.R)
+	// Project onto a plane where the six directions are evenly spaced, with
+	// Direction 0 along +x and the rest following counter-clockwise.
+	x := dq + dr/2
+	y := -dr * math.Sqrt(3) / 2
+	return math.Atan2(y, x)
+}
+
+// DirectionTowards returns the hex direction closest to the line from one
+// hex to another. A target exactly halfway between two directions goes to
+// the lower-numbered one. It returns 0 when from and to are the same hex.
+func DirectionTowards(from, to Hex) Direction {
+	// Pick the direction whose offset has the largest dot product with the
+	// line, worked in integers (four times the dot product of the points on
+	// the plane Angle uses) so ties are exact.
+	dq, dr := to.Q-from.Q, to.R-from.R
+	best, bestDot := Direction(0), int64(math.MinInt64)
+	for d, dir := range directions {
+		if dot := (2*dq+dr)*(2*dir.Q+dir.R) + 3*dr*dir.R; dot > bestDot {
+			best, bestDot = Direction(d), dot
+		}
+	}
+	return best
+}
@@ -0,0 +1,92 @@
+package hex
+
+// Edge is the border between two adjacent hexes, for walls, doors, rivers
+// and fences. It is stored canonically as the hex on one side and the
+// direction (0, 1 or 2) of the hex on the other, so each border has exactly
+// one Edge value and edges can be used as map keys.
+type Edge struct {
+	Hex  Hex       `json:"hex"`
+	Side Direction `json:"side"`
+}
+
+// Vertex is a corner shared by three hexes. Corner k of a hex sits between
+// its neighbors in directions k and k+1. Every vertex is corner 0 or corner
+// 1 of exactly one hex, which is how it is stored.
+type Vertex struct {
+	Hex    Hex
+	Corner int
+}
+
+// EdgeOf returns the edge on side d of h.
+func EdgeOf(h Hex, d Direction) Edge {
+	d = d.normalize()
+	if d >= 3 {
+		return Edge{Hex: Neighbor(h, d), Side: d - 3}
+	}
+	return Edge{Hex: h, Side: d}
+}
+
+// EdgeBetween returns the edge separating two hexes, and false if they are
+// not neighbors.
+func EdgeBetween(a, b Hex) (Edge, bool) {
+	for d, offset := range directions {
+		if a.Q+offset.Q == b.Q && a.R+offset.R == b.R {
+			return EdgeOf(a, Direction(d)), true
+		}
+	}
+	return Edge{}, false
+}
+
+// Hexes returns the two hexes on either side of the edge.
+func (e Edge) Hexes() (Hex, Hex) {
+	return e.Hex, Neighbor(e.Hex, e.Side)
+}
+
+// Vertices returns the two corners at the ends of the edge.
+func (e Edge) Vertices() (Vertex, Vertex) {
+	return VertexOf(e.Hex, int(e.Side)-1), VertexOf(e.Hex, int(e.Side))
+}
+
+// Edges returns the six edges around h, indexed by direction.
+func Edges(h Hex) [6]Edge {
+	var edges [6]Edge
+	for d := range edges {
+		edges[d] = EdgeOf(h, Direction(d))
+	}
+	return edges
+}
+
+// VertexOf returns corner k of h.
+func VertexOf(h Hex, corner int) Vertex {
+	corner = ((corner % 6) + 6) % 6
+	// Corner k of h is also corner k+2 of its neighbor in direction k and
+	// corner k+4 of its neighbor in direction k+1. Exactly one of the three
+	// is 0 or 1.
+	switch corner {
+	case 0, 1:
+		return Vertex{Hex: h, Corner: corner}
+	case 4, 5:
+		return Vertex{Hex: Neighbor(h, Direction(corner)), Corner: corner - 4}
+	default:
+		return Vertex{Hex: Neighbor(h, Direction(corner+1)), Corner: corner - 2}
+	}
+}
+
+// Hexes returns the three hexes that meet at the vertex.
+func (v Vertex) Hexes() [3]Hex {
+	d := Direction(v.Corner)
+	return [3]Hex{v.Hex, Neighbor(v.Hex, d), Neighbor(v.Hex, d+1)}
+}
+
+// Edges returns the three edges that meet at the vertex.
+func (v Vertex) Edges() [3]Edge {
+	hexes := v.Hexes()
+	d := Direction(v.Corner)
+	return [3]Edge{
+		EdgeOf(hexes[0], d),
+		EdgeOf(hexes[0], d+1),
+		// The edge between the two neighbors runs in direction d+2 from the
+		// first of them.
+		EdgeOf(hexes[1], d+2),
+	}
+}
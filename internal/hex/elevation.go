@@ -0,0 +1,176 @@
+package hex
+
+import (
+	"iter"
+	"math"
+	"slices"
+
+	hx "github.com/gojuno/go.hexgrid"
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// heightOffset is how many pixels up the screen height lifts a hex.
+func (l *Layout) heightOffset(height float64) float64 {
+	return height * l.config.HeightScale * l.config.Size
+}
+
+// HexToPixelAt returns the center of the top face of a hex raised to height.
+func (l *Layout) HexToPixelAt(q, r int64, height float64) (float64, float64) {
+	x, y := l.HexToPixel(q, r)
+	return x, y - l.heightOffset(height)
+}
+
+// SideWalls returns a quad for every side of h that faces the viewer and
+// drops down to a lower neighbor. Each quad runs top-left, top-right,
+// bottom-right, bottom-left along the edge, ready to be split into two
+// triangles. Back-facing sides are hidden behind the top face and skipped.
+func (l *Layout) SideWalls(h Hex, height func(Hex) float64) [][]ebiten.Vertex {
+	top := height(h)
+	_, cy := l.HexToPixel(h.Q, h.R)
+	walls := [][]ebiten.Vertex{}
+
+	for d := Direction(0); d < 6; d++ {
+		bottom := height(Neighbor(h, d))
+		if bottom >= top {
+			continue
+		}
+
+		x1, y1, x2, y2 := l.EdgeToPixels(EdgeOf(h, d))
+		if (y1+y2)/2 <= cy {
+			continue // faces away from the viewer
+		}
+		if x1 > x2 {
+			x1, y1, x2, y2 = x2, y2, x1, y1
+		}
+
+		liftTop, liftBottom := l.heightOffset(top), l.heightOffset(bottom)
+		walls = append(walls, []ebiten.Vertex{
+			wallVertex(x1, y1-liftTop),
+			wallVertex(x2, y2-liftTop),
+			wallVertex(x2, y2-liftBottom),
+			wallVertex(x1, y1-liftBottom),
+		})
+	}
+	return walls
+}
+
+func wallVertex(x, y float64) ebiten.Vertex {
+	return ebiten.Vertex{
+		DstX:   float32(x),
+		DstY:   float32(y),
+		ColorR: 1,
+		ColorG: 1,
+		ColorB: 1,
+		ColorA: 1,
+	}
+}
+
+// PaintersOrder yields the hexes back to front for this layout, so raised
+// hexes and their walls overlap whatever stands behind them.
+func (l *Layout) PaintersOrder(hexes []Hex) iter.Seq[Hex] {
+	sorted := slices.Clone(hexes)
+	slices.SortFunc(sorted, func(a, b Hex) int {
+		ax, ay := l.HexToPixel(a.Q, a.R)
+		bx, by := l.HexToPixel(b.Q, b.R)
+		if ay != by {
+			return cmpFloat(ay, by)
+		}
+		return cmpFloat(ax, bx)
+	})
+	return slices.Values(sorted)
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// PickHex returns the hex drawn on top at screen position (x, y) when every
+// hex is raised to its height, which PixelToHex alone gets wrong as soon as
+// a raised hex hides the ground behind it. contains limits the search to the
+// hexes that exist, and maxHeight bounds how far any hex is raised.
+func (l *Layout) PickHex(x, y float64, contains func(Hex) bool, height func(Hex) float64, maxHeight float64) (Hex, bool) {
+	// Anything drawn at (x, y) sits on the ground somewhere below it on
+	// screen, at most maxHeight worth of lift further down.
+	candidates := map[Hex]bool{}
+	step := l.config.Size * l.config.IsoScaleY / 2
+	reach := l.heightOffset(math.Max(maxHeight, 0))
+	for dy := 0.0; dy <= reach+step; dy += step {
+		q, r := l.PixelToHex(x, y+dy)
+		base := Hex{Q: q, R: r}
+		for _, h := range append(GetNeighbors(base), base) {
+			if contains(h) {
+				candidates[h] = true
+			}
+		}
+	}
+
+	hexes := make([]Hex, 0, len(candidates))
+	for h := range candidates {
+		hexes = append(hexes, h)
+	}
+	ordered := slices.Collect(l.PaintersOrder(hexes))
+
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if l.columnContains(ordered[i], height(ordered[i]), x, y) {
+			return ordered[i], true
+		}
+	}
+	return Hex{}, false
+}
+
+// columnContains reports whether the screen point falls on a hex drawn as a
+// column from its top face down to the ground.
+func (l *Layout) columnContains(h Hex, height, x, y float64) bool {
+	corners := l.hexgrid.HexCorners(hx.MakeHex(h.Q, h.R))
+	top := l.heightOffset(height)
+	bottom := l.heightOffset(math.Min(height, 0))
+
+	var topFace, bottomFace [6][2]float64
+	for i, c := range corners {
+		topFace[i] = [2]float64{c.X(), c.Y() - top}
+		bottomFace[i] = [2]float64{c.X(), c.Y() - bottom}
+	}
+	if insideConvex(topFace[:], x, y) || insideConvex(bottomFace[:], x, y) {
+		return true
+	}
+	if top == bottom {
+		return false
+	}
+	for i := range topFace {
+		j := (i + 1) % len(topFace)
+		side := [][2]float64{topFace[i], topFace[j], bottomFace[j], bottomFace[i]}
+		if insideConvex(side, x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// insideConvex reports whether (x, y) lies inside or on a convex polygon
+// given in either winding order. A degenerate polygon contains nothing.
+func insideConvex(polygon [][2]float64, x, y float64) bool {
+	sign := 0
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		cross := (b[0]-a[0])*(y-a[1]) - (b[1]-a[1])*(x-a[0])
+		switch {
+		case cross > 0:
+			if sign < 0 {
+				return false
+			}
+			sign = 1
+		case cross < 0:
+			if sign > 0 {
+				return false
+			}
+			sign = -1
+		}
+	}
+	return sign != 0
+}
@@ -0,0 +1,138 @@
+package hex
+
+import "slices"
+
+// Occlusion decides how much of a hex has to be out of shadow for
+// FieldOfView to report it as visible.
+type Occlusion int
+
+const (
+	// OcclusionCenter shows a hex when its center is not in shadow.
+	OcclusionCenter Occlusion = iota
+	// OcclusionPartial shows a hex when any part of it is lit.
+	OcclusionPartial
+	// OcclusionFull shows a hex only when no part of it is in shadow.
+	OcclusionFull
+)
+
+// shadowEpsilon absorbs floating point error when comparing arc ends.
+const shadowEpsilon = 1e-9
+
+// arc is a slice of the full turn around the viewer, in [0, 1].
+type arc struct {
+	start, end float64
+}
+
+// shadows is a sorted list of non-overlapping arcs blocked from view.
+type shadows []arc
+
+// FieldOfView returns every hex within radius of origin that the origin can
+// see, using ring-by-ring shadowcasting. Each hex is visited once: a hex at
+// index i of ring r spans the arc [(i-0.5)/6r, (i+0.5)/6r], and every
+// blocking hex adds its arc to the shadow cast onto the rings behind it.
+// Blocking hexes are themselves visible when lit.
+func FieldOfView(origin Hex, radius int64, isBlocking func(Hex) bool, occlusion Occlusion) []Hex {
+	visible := []Hex{origin}
+	var shade shadows
+
+	for r := int64(1); r <= radius; r++ {
+		hexes := Ring(origin, r)
+		n := float64(len(hexes))
+
+		for i, h := range hexes {
+			start := (float64(i) - 0.5) / n
+			end := (float64(i) + 0.5) / n
+
+			if shade.lit(start, end, occlusion) {
+				visible = append(visible, h)
+			}
+			if isBlocking(h) {
+				shade.add(start, end)
+			}
+		}
+
+		if shade.coversAll() {
+			break
+		}
+	}
+
+	return visible
+}
+
+// lit reports whether the arc [start, end] passes the occlusion rule.
+func (s shadows) lit(start, end float64, occlusion Occlusion) bool {
+	switch occlusion {
+	case OcclusionPartial:
+		return !s.covers(start, end)
+	case OcclusionFull:
+		return !s.overlaps(start, end)
+	default:
+		// Widen the center past the comparison tolerance so a shadow that
+		// only reaches the center, as along an edge between two hexes, does
+		// not count as covering it.
+		center := (start + end) / 2
+		return !s.covers(center-2*shadowEpsilon, center+2*shadowEpsilon)
+	}
+}
+
+// covers reports whether a single shadow contains the whole arc, which may
+// wrap around 0.
+func (s shadows) covers(start, end float64) bool {
+	if start < 0 {
+		return s.covers(start+1, 1) && s.covers(0, end)
+	}
+	for _, a := range s {
+		if a.start <= start+shadowEpsilon && a.end >= end-shadowEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether any shadow intersects the inside of the arc.
+func (s shadows) overlaps(start, end float64) bool {
+	if start < 0 {
+		return s.overlaps(start+1, 1) || s.overlaps(0, end)
+	}
+	for _, a := range s {
+		if a.start < end-shadowEpsilon && a.end > start+shadowEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// add merges the arc into the shadow list, joining any arcs it touches.
+func (s *shadows) add(start, end float64) {
+	if start < 0 {
+		s.add(start+1, 1)
+		start = 0
+	}
+
+	merged := arc{start: start, end: end}
+	kept := (*s)[:0]
+	for _, a := range *s {
+		if a.end < merged.start-shadowEpsilon || a.start > merged.end+shadowEpsilon {
+			kept = append(kept, a)
+			continue
+		}
+		merged.start = min(merged.start, a.start)
+		merged.end = max(merged.end, a.end)
+	}
+
+	kept = append(kept, merged)
+	slices.SortFunc(kept, func(a, b arc) int {
+		switch {
+		case a.start < b.start:
+			return -1
+		case a.start > b.start:
+			return 1
+		}
+		return 0
+	})
+	*s = kept
+}
+
+func (s shadows) coversAll() bool {
+	return s.covers(0, 1)
+}
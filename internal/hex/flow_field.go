@@ -0,0 +1,146 @@
+package hex
+
+import (
+	"container/heap"
+	"math"
+	"slices"
+)
+
+// DefaultFleeScale is how strongly Flee prefers distance over the nearest
+// exit. Values just above 1 make cornered units break past their pursuers
+// instead of cowering in a dead end.
+const DefaultFleeScale = 1.2
+
+// FlowField is a distance map (a Dijkstra map) toward one or more goals. It
+// is built once, after which any number of units read the cost of getting
+// home and their next step in constant time, instead of each running its
+// own path search.
+type FlowField struct {
+	nodes      map[Hex]flowNode
+	goals      map[Hex]bool
+	isWalkable func(Hex) bool
+	cost       CostFunc
+}
+
+type flowNode struct {
+	value int
+	next  Hex
+}
+
+// NewFlowField floods outward from goals over walkable hexes, recording how
+// much it costs to walk from each hex to the nearest goal. cost is charged
+// for the step a unit would take toward the goal, matching FindWeightedPath.
+// maxCost stops the flood early; 0 floods everything isWalkable allows, so
+// isWalkable must then bound the region.
+func NewFlowField(goals []Hex, maxCost int, isWalkable func(Hex) bool, cost CostFunc) *FlowField {
+	seeds := make(map[Hex]int, len(goals))
+	for _, g := range goals {
+		seeds[g] = 0
+	}
+	f := &FlowField{goals: make(map[Hex]bool, len(goals)), isWalkable: isWalkable, cost: cost}
+	for _, g := range goals {
+		f.goals[g] = true
+	}
+	f.flood(goals, seeds, maxCost)
+	return f
+}
+
+// flood runs a multi-source Dijkstra from order, whose starting values come
+// from seeds. A hex's value ends up as the smaller of its own seed and the
+// cheapest way to walk to a neighbor plus that neighbor's value.
+func (f *FlowField) flood(order []Hex, seeds map[Hex]int, maxCost int) {
+	f.nodes = make(map[Hex]flowNode, len(seeds))
+	closedSet := make(map[Hex]bool)
+	openSet := &PriorityQueue{}
+	heap.Init(openSet)
+	for _, h := range order {
+		f.nodes[h] = flowNode{value: seeds[h], next: h}
+		heap.Push(openSet, &PathNode{hex: h, fScore: seeds[h]})
+	}
+
+	for openSet.Len() > 0 {
+		current := heap.Pop(openSet).(*PathNode).hex
+		if closedSet[current] {
+			continue
+		}
+		closedSet[current] = true
+
+		for _, neighbor := range GetNeighbors(current) {
+			if closedSet[neighbor] || !f.isWalkable(neighbor) {
+				continue
+			}
+			value := f.nodes[current].value + f.cost(neighbor, current)
+			if maxCost > 0 && value > maxCost {
+				continue
+			}
+			// On a tie, stepping beats standing on a seed: values strictly
+			// fall along every step, so units cannot loop.
+			if node, exists := f.nodes[neighbor]; exists && (node.value < value || node.value == value && node.next != neighbor) {
+				continue
+			}
+			f.nodes[neighbor] = flowNode{value: value, next: current}
+			heap.Push(openSet, &PathNode{hex: neighbor, fScore: value})
+		}
+	}
+}
+
+// Flee returns the field a unit runs down to get away from this field's
+// goals. Every value is multiplied by -scale and the map is flooded again,
+// so units head for the far side of the region but still prefer a short
+// detour past danger to a dead end. The goals themselves, where the danger
+// stands, are never stepped on. Use DefaultFleeScale unless tuning.
+func (f *FlowField) Flee(scale float64) *FlowField {
+	hexes := make([]Hex, 0, len(f.nodes))
+	seeds := make(map[Hex]int, len(f.nodes))
+	for h, node := range f.nodes {
+		if f.goals[h] {
+			continue
+		}
+		hexes = append(hexes, h)
+		seeds[h] = int(math.Round(-scale * float64(node.value)))
+	}
+	// Seed in a fixed order so ties break the same way on every run.
+	slices.SortFunc(hexes, compareDrawOrder)
+
+	flee := &FlowField{
+		isWalkable: func(h Hex) bool { _, ok := seeds[h]; return ok },
+		cost:       f.cost,
+	}
+	flee.flood(hexes, seeds, 0)
+	return flee
+}
+
+// Value returns the field's value at h: the cost to the nearest goal for a
+// field from NewFlowField. It returns false for hexes the flood never
+// reached.
+func (f *FlowField) Value(h Hex) (int, bool) {
+	node, ok := f.nodes[h]
+	return node.value, ok
+}
+
+// Next returns the neighbor a unit on h should step to. It returns false on
+// a goal, a local minimum of a flee field, and hexes the flood never reached.
+func (f *FlowField) Next(h Hex) (Hex, bool) {
+	node, ok := f.nodes[h]
+	if !ok || node.next == h {
+		return h, false
+	}
+	return node.next, true
+}
+
+// PathFrom follows the field from h until it stops, returning every hex
+// along the way with h first. It returns nil if the flood never reached h.
+func (f *FlowField) PathFrom(h Hex) []Hex {
+	if _, ok := f.nodes[h]; !ok {
+		return nil
+	}
+	path := []Hex{h}
+	for {
+		next, ok := f.Next(h)
+		if !ok {
+			return path
+		}
+		path = append(path, next)
+		h = next
+	}
+}
@@ -0,0 +1,105 @@
+package hex
+
+// Footprint is the set of hexes an entity covers, as offsets from the anchor
+// hex that marks its position. Single-hex units have the footprint {{0,0}}.
+type Footprint []Hex
+
+// HexagonFootprint covers every hex within radius of the anchor, the shape
+// of a boss with SizeData.Radius.
+func HexagonFootprint(radius int64) Footprint {
+	return Footprint(Range(Hex{}, radius))
+}
+
+// At returns the hexes the footprint covers when anchored at anchor.
+func (f Footprint) At(anchor Hex) []Hex {
+	hexes := make([]Hex, len(f))
+	for i, offset := range f {
+		hexes[i] = Hex{Q: anchor.Q + offset.Q, R: anchor.R + offset.R}
+	}
+	return hexes
+}
+
+// Covers reports whether h is one of the footprint's hexes at anchor.
+func (f Footprint) Covers(anchor, h Hex) bool {
+	for _, offset := range f {
+		if anchor.Q+offset.Q == h.Q && anchor.R+offset.R == h.R {
+			return true
+		}
+	}
+	return false
+}
+
+// Fits reports whether every hex of the footprint at anchor is walkable.
+func (f Footprint) Fits(anchor Hex, isWalkable func(Hex) bool) bool {
+	for _, offset := range f {
+		if !isWalkable(Hex{Q: anchor.Q + offset.Q, R: anchor.R + offset.R}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Distance returns the number of steps from the nearest hex of the
+// footprint at anchor to h, or 0 if the footprint covers h.
+func (f Footprint) Distance(anchor, h Hex) int64 {
+	nearest := int64(-1)
+	for _, covered := range f.At(anchor) {
+		if d := HexDistance(covered, h); nearest < 0 || d < nearest {
+			nearest = d
+		}
+	}
+	return max(nearest, 0)
+}
+
+// IsAdjacent reports whether h touches the footprint at anchor without being
+// part of it, which is what melee reach against a large entity needs.
+func (f Footprint) IsAdjacent(anchor, h Hex) bool {
+	return f.Distance(anchor, h) == 1
+}
+
+// Walkable turns a per-hex isWalkable callback into one over anchors, true
+// when the whole footprint fits.
+func (f Footprint) Walkable(isWalkable func(Hex) bool) func(Hex) bool {
+	return func(anchor Hex) bool {
+		return f.Fits(anchor, isWalkable)
+	}
+}
+
+// Cost charges a move by the most expensive hex the footprint lands on.
+func (f Footprint) Cost(cost func(Hex) int) CostFunc {
+	return func(_, to Hex) int {
+		highest := 0
+		for _, h := range f.At(to) {
+			highest = max(highest, cost(h))
+		}
+		return highest
+	}
+}
+
+// Occupant reports the most restrictive occupant under the footprint: any
+// enemy blocks the move, any ally makes it pass-through only.
+func (f Footprint) Occupant(occupant func(Hex) Occupant) func(Hex) Occupant {
+	return func(anchor Hex) Occupant {
+		worst := Unoccupied
+		for _, h := range f.At(anchor) {
+			worst = max(worst, occupant(h))
+		}
+		return worst
+	}
+}
+
+// FindFootprintPath finds the cheapest path for the footprint's anchor from
+// start to goal such that the whole footprint stays walkable at every step.
+// isWalkable should not treat the entity's own hexes as blocked.
+func FindFootprintPath(start, goal Hex, footprint Footprint, isWalkable func(Hex) bool, cost CostFunc, minCost int) ([]Hex, int) {
+	return FindWeightedPath(start, goal, footprint.Walkable(isWalkable), cost, minCost)
+}
+
+// ReachableFootprint is Reachable for a multi-hex entity: every anchor in
+// the result has the whole footprint on walkable hexes.
+func ReachableFootprint(origin Hex, budget int, footprint Footprint, isWalkable func(Hex) bool, cost CostFunc, occupant func(Hex) Occupant) *Reach {
+	if occupant != nil {
+		occupant = footprint.Occupant(occupant)
+	}
+	return Reachable(origin, budget, footprint.Walkable(isWalkable), cost, occupant)
+}
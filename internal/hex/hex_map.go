@@ -0,0 +1,207 @@
+package hex
+
+import (
+	"iter"
+	"slices"
+)
+
+// Map stores a value of type T for every hex in a fixed shape. Hexes are
+// kept in draw order for the map's orientation: top to bottom, then left to
+// right, so drawing them in sequence overlaps correctly.
+type Map[T any] struct {
+	hexes       []Hex
+	values      []T
+	index       map[Hex]int
+	orientation Orientation
+}
+
+// NewMap builds a map over an arbitrary set of hexes, to be drawn flat-top.
+// Duplicates are ignored.
+func NewMap[T any](hexes []Hex) *Map[T] {
+	return NewOrientedMap[T](hexes, Flat)
+}
+
+// NewOrientedMap is NewMap for a map drawn with the given orientation.
+func NewOrientedMap[T any](hexes []Hex, orientation Orientation) *Map[T] {
+	m := &Map[T]{
+		hexes:       make([]Hex, 0, len(hexes)),
+		index:       make(map[Hex]int, len(hexes)),
+		orientation: orientation,
+	}
+	for _, h := range hexes {
+		if _, exists := m.index[h]; exists {
+			continue
+		}
+		m.index[h] = 0
+		m.hexes = append(m.hexes, h)
+	}
+
+	if orientation == Pointy {
+		slices.SortFunc(m.hexes, comparePointyDrawOrder)
+	} else {
+		slices.SortFunc(m.hexes, compareDrawOrder)
+	}
+	for i, h := range m.hexes {
+		m.index[h] = i
+	}
+	m.values = make([]T, len(m.hexes))
+	return m
+}
+
+// NewHexagonalMap builds a hexagon of the given radius around (0,0).
+func NewHexagonalMap[T any](radius int64) *Map[T] {
+	return NewMap[T](Range(Hex{}, radius))
+}
+
+// NewRectangularMap builds a screen-aligned rectangle of width columns and
+// height rows for flat-top hexes, with (0,0) in the top left corner. Odd
+// columns are shifted down half a hex.
+func NewRectangularMap[T any](width, height int64) *Map[T] {
+	return NewOffsetRectangularMap[T](width, height, OddQ)
+}
+
+// NewOffsetRectangularMap builds the width by height rectangle of an offset
+// coordinate system, so a map imported from an editor keeps its shape. The
+// map is kept in draw order for system.Orientation(); use a layout with
+// that orientation to draw it.
+func NewOffsetRectangularMap[T any](width, height int64, system OffsetSystem) *Map[T] {
+	hexes := make([]Hex, 0, width*height)
+	for col := int64(0); col < width; col++ {
+		for row := int64(0); row < height; row++ {
+			hexes = append(hexes, FromOffset(OffsetCoord{Col: col, Row: row}, system))
+		}
+	}
+	return NewOrientedMap[T](hexes, system.Orientation())
+}
+
+// NewParallelogramMap builds every hex with q in [minQ, maxQ] and r in
+// [minR, maxR].
+func NewParallelogramMap[T any](minQ, maxQ, minR, maxR int64) *Map[T] {
+	hexes := []Hex{}
+	for q := minQ; q <= maxQ; q++ {
+		for r := minR; r <= maxR; r++ {
+			hexes = append(hexes, Hex{Q: q, R: r})
+		}
+	}
+	return NewMap[T](hexes)
+}
+
+// NewTriangularMap builds a triangle with size+1 hexes along each side and
+// its corner at (0,0).
+func NewTriangularMap[T any](size int64) *Map[T] {
+	hexes := []Hex{}
+	for q := int64(0); q <= size; q++ {
+		for r := int64(0); r <= size-q; r++ {
+			hexes = append(hexes, Hex{Q: q, R: r})
+		}
+	}
+	return NewMap[T](hexes)
+}
+
+// compareDrawOrder sorts by screen y for a flat-top layout (which grows with
+// q+2r), then by screen x.
+func compareDrawOrder(a, b Hex) int {
+	if ya, yb := a.Q+2*a.R, b.Q+2*b.R; ya != yb {
+		return int(ya - yb)
+	}
+	return int(a.Q - b.Q)
+}
+
+// comparePointyDrawOrder sorts by screen y for a pointy-top layout (which
+// grows with r), then by screen x (which grows with 2q+r).
+func comparePointyDrawOrder(a, b Hex) int {
+	if a.R != b.R {
+		return int(a.R - b.R)
+	}
+	return int(a.Q - b.Q)
+}
+
+// Orientation returns the layout the map keeps its draw order for.
+func (m *Map[T]) Orientation() Orientation {
+	return m.orientation
+}
+
+func (m *Map[T]) Len() int {
+	return len(m.hexes)
+}
+
+// Contains reports whether h is part of the map's shape. It has the
+// signature of an isWalkable callback, so FindPath(a, b, m.Contains) keeps a
+// search inside the map.
+func (m *Map[T]) Contains(h Hex) bool {
+	_, ok := m.index[h]
+	return ok
+}
+
+// Get returns the value stored at h, or the zero value and false if h is
+// outside the map.
+func (m *Map[T]) Get(h Hex) (T, bool) {
+	i, ok := m.index[h]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return m.values[i], true
+}
+
+// Set stores value at h. It returns false, and stores nothing, when h is
+// outside the map.
+func (m *Map[T]) Set(h Hex, value T) bool {
+	i, ok := m.index[h]
+	if !ok {
+		return false
+	}
+	m.values[i] = value
+	return true
+}
+
+// Fill sets every hex to the value returned by fn.
+func (m *Map[T]) Fill(fn func(Hex) T) {
+	for i, h := range m.hexes {
+		m.values[i] = fn(h)
+	}
+}
+
+// Hexes returns a copy of the map's hexes in draw order.
+func (m *Map[T]) Hexes() []Hex {
+	return slices.Clone(m.hexes)
+}
+
+// All iterates over every hex and its value in draw order.
+func (m *Map[T]) All() iter.Seq2[Hex, T] {
+	return func(yield func(Hex, T) bool) {
+		for i, h := range m.hexes {
+			if !yield(h, m.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Neighbors returns the neighbors of h that are inside the map.
+func (m *Map[T]) Neighbors(h Hex) []Hex {
+	neighbors := make([]Hex, 0, 6)
+	for _, n := range GetNeighbors(h) {
+		if m.Contains(n) {
+			neighbors = append(neighbors, n)
+		}
+	}
+	return neighbors
+}
+
+// Walkable returns an isWalkable callback that accepts hexes inside the map
+// whose value passes the predicate.
+func (m *Map[T]) Walkable(passable func(T) bool) func(Hex) bool {
+	return func(h Hex) bool {
+		i, ok := m.index[h]
+		return ok && passable(m.values[i])
+	}
+}
+
+// Cost returns a CostFunc that charges the cost of the value being entered.
+func (m *Map[T]) Cost(cost func(T) int) CostFunc {
+	return func(_, to Hex) int {
+		value, _ := m.Get(to)
+		return cost(value)
+	}
+}
@@ -1,8 +1,18 @@
 package hex
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"math"
+	"math/rand/v2"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestHexToPixel verifies hex coordinates convert to pixel coordinates
@@ -45,7 +55,7 @@ func TestPixelToHex(t *testing.T) {
 		x, y         float64
 		wantQ, wantR int64
 	}{
-		{"center of screen", OffsetX, OffsetY, 0, 0},
+		{"center of screen", DefaultOriginX, DefaultOriginY, 0, 0},
 		// Add more tests after you verify the first one works
 	}
 
@@ -91,3 +101,2229 @@ func TestRoundTrip(t *testing.T) {
 		})
 	}
 }
+
+// TestLayoutsAreIndependent verifies zooming or moving one layout leaves
+// another untouched
+func TestLayoutsAreIndependent(t *testing.T) {
+	t.Parallel()
+
+	mainView := NewLayout()
+	minimap := NewLayoutWithConfig(LayoutConfig{
+		Orientation: Flat,
+		Size:        8,
+		OriginX:     100,
+		OriginY:     100,
+		IsoScaleX:   1,
+		IsoScaleY:   1,
+	})
+	beforeX, beforeY := mainView.HexToPixel(2, -1)
+
+	minimap.ZoomIn()
+	minimap.SetOrigin(50, 50)
+
+	if x, y := mainView.HexToPixel(2, -1); x != beforeX || y != beforeY {
+		t.Errorf("main layout moved from (%f,%f) to (%f,%f)", beforeX, beforeY, x, y)
+	}
+	if x, y := minimap.HexToPixel(0, 0); x != 50 || y != 50 {
+		t.Errorf("minimap origin = (%f,%f), want (50,50)", x, y)
+	}
+	if q, r := minimap.PixelToHex(minimap.HexToPixel(3, -2)); q != 3 || r != -2 {
+		t.Errorf("minimap round trip = (%d,%d), want (3,-2)", q, r)
+	}
+}
+
+// TestLayoutRoundTripAllConfigs verifies picking after zoom and for both orientations
+func TestLayoutRoundTripAllConfigs(t *testing.T) {
+	t.Parallel()
+
+	for _, orientation := range []Orientation{Flat, Pointy} {
+		config := DefaultLayoutConfig()
+		config.Orientation = orientation
+		layout := NewLayoutWithConfig(config)
+		layout.ZoomIn()
+		layout.ZoomIn()
+
+		for q := int64(-4); q <= 4; q++ {
+			for r := int64(-4); r <= 4; r++ {
+				if gotQ, gotR := layout.PixelToHex(layout.HexToPixel(q, r)); gotQ != q || gotR != r {
+					t.Errorf("orientation %d: (%d,%d) round tripped to (%d,%d)", orientation, q, r, gotQ, gotR)
+				}
+			}
+		}
+	}
+}
+
+// TestLayoutConversionsDoNotAllocate verifies the grid is cached between calls
+func TestLayoutConversionsDoNotAllocate(t *testing.T) {
+	layout := NewLayout()
+	allocs := testing.AllocsPerRun(100, func() {
+		x, y := layout.HexToPixel(3, -1)
+		layout.PixelToHex(x, y)
+	})
+	if allocs != 0 {
+		t.Errorf("conversions allocated %.0f times per run", allocs)
+	}
+}
+
+// TestCameraZoomKeepsCursorAnchored verifies the point under the cursor stays
+// under it on every frame of a smooth zoom
+func TestCameraZoomKeepsCursorAnchored(t *testing.T) {
+	layout := NewLayout()
+	camera := NewCamera(layout, 1280, 720)
+
+	cursorX, cursorY := layout.HexToPixel(3, -1)
+	cursorX += 5
+	wantQ, wantR := layout.PixelToHex(cursorX, cursorY)
+
+	camera.ZoomAt(cursorX, cursorY, 2)
+	for frame := 0; frame < 100 && !camera.Settled(); frame++ {
+		camera.Update()
+		if q, r := layout.PixelToHex(cursorX, cursorY); q != wantQ || r != wantR {
+			t.Fatalf("frame %d: cursor picks (%d,%d), want (%d,%d)", frame, q, r, wantQ, wantR)
+		}
+	}
+
+	if !camera.Settled() {
+		t.Fatal("camera never settled")
+	}
+	if math.Abs(layout.Size()-2*DefaultHexSize) > 1e-9 {
+		t.Errorf("size = %f, want %f", layout.Size(), 2*DefaultHexSize)
+	}
+	// The 5px offset from the hex center doubles with the zoom
+	x, y := layout.HexToPixel(3, -1)
+	if math.Abs(x+10-cursorX) > 1e-6 || math.Abs(y-cursorY) > 1e-6 {
+		t.Errorf("hex (3,-1) at (%f,%f), want (%f,%f)", x, y, cursorX-10, cursorY)
+	}
+}
+
+// TestCameraPanAndClamp verifies dragging moves the map and stops at its edges
+func TestCameraPanAndClamp(t *testing.T) {
+	layout := NewLayout()
+	camera := NewCamera(layout, 1280, 720)
+	camera.Smoothing = 1
+
+	beforeX, beforeY := layout.HexToPixel(0, 0)
+	camera.Pan(30, -20)
+	if x, y := layout.HexToPixel(0, 0); x != beforeX+30 || y != beforeY-20 {
+		t.Errorf("pan moved (0,0) to (%f,%f), want (%f,%f)", x, y, beforeX+30, beforeY-20)
+	}
+
+	// A big rectangular map can be panned, but never past its edges
+	big := []Hex{}
+	for q := int64(-30); q <= 30; q++ {
+		for row := int64(-30); row <= 30; row++ {
+			big = append(big, Hex{q, row - q>>1})
+		}
+	}
+	camera.SetBounds(big)
+	for _, drag := range [][2]float64{{1e6, 1e6}, {-1e6, -1e6}} {
+		camera.Pan(drag[0], drag[1])
+		for _, corner := range [][2]float64{{0, 0}, {1279, 0}, {0, 719}, {1279, 719}} {
+			q, r := layout.PixelToHex(corner[0], corner[1])
+			if row := r + q>>1; q < -31 || q > 31 || row < -31 || row > 31 {
+				t.Errorf("after drag %v screen corner %v shows (%d,%d), outside the map", drag, corner, q, r)
+			}
+		}
+	}
+
+	// A small map is centered and cannot be moved
+	camera.SetBounds([]Hex{{0, 0}})
+	camera.Pan(200, 200)
+	if x, y := layout.HexToPixel(0, 0); math.Abs(x-640) > 1e-9 || math.Abs(y-360) > 1e-9 {
+		t.Errorf("single hex map at (%f,%f), want screen center", x, y)
+	}
+}
+
+// TestCameraEdgeScroll verifies the cursor near an edge scrolls the view
+func TestCameraEdgeScroll(t *testing.T) {
+	layout := NewLayout()
+	camera := NewCamera(layout, 1280, 720)
+	camera.Smoothing = 1
+
+	camera.EdgeScroll(640, 360)
+	camera.Update()
+	if x, y := layout.Origin(); x != DefaultOriginX || y != DefaultOriginY {
+		t.Errorf("cursor in the middle scrolled the view to (%f,%f)", x, y)
+	}
+
+	camera.EdgeScroll(1279, 2)
+	camera.Update()
+	if x, y := layout.Origin(); x != DefaultOriginX-camera.EdgeSpeed || y != DefaultOriginY+camera.EdgeSpeed {
+		t.Errorf("origin = (%f,%f), want (%f,%f)", x, y, DefaultOriginX-camera.EdgeSpeed, DefaultOriginY+camera.EdgeSpeed)
+	}
+}
+
+// TestFindPathWeighted verifies the search detours around expensive terrain
+// and reports the total cost of the path it picks
+func TestFindPathWeighted(t *testing.T) {
+	swamp := map[Hex]bool{{1, 0}: true, {2, 0}: true, {1, -1}: true}
+	walkable := func(h Hex) bool { return HexDistance(Hex{}, h) <= 4 }
+	cost := HexCost(func(h Hex) int {
+		if swamp[h] {
+			return 5
+		}
+		return 1
+	})
+
+	path, total := FindWeightedPath(Hex{0, 0}, Hex{3, 0}, walkable, cost, 1)
+	if path == nil {
+		t.Fatal("expected a path")
+	}
+	if path[0] != (Hex{0, 0}) || path[len(path)-1] != (Hex{3, 0}) {
+		t.Errorf("path endpoints = %v, %v", path[0], path[len(path)-1])
+	}
+
+	sum := 0
+	for i := 1; i < len(path); i++ {
+		if swamp[path[i]] {
+			t.Errorf("path steps into swamp at %v", path[i])
+		}
+		sum += cost(path[i-1], path[i])
+	}
+	if sum != total {
+		t.Errorf("reported cost %d, summed cost %d", total, sum)
+	}
+	if total != 4 {
+		t.Errorf("cost = %d, want 4", total)
+	}
+
+	// Dijkstra (minCost 0) must agree with the scaled heuristic
+	_, dijkstra := FindWeightedPath(Hex{0, 0}, Hex{3, 0}, walkable, cost, 0)
+	if dijkstra != total {
+		t.Errorf("Dijkstra cost %d, A* cost %d", dijkstra, total)
+	}
+}
+
+// TestFindPathUnreachable verifies a walled-off goal yields no path
+func TestFindPathUnreachable(t *testing.T) {
+	goal := Hex{2, 0}
+	walkable := func(h Hex) bool {
+		return HexDistance(Hex{}, h) <= 3 && HexDistance(goal, h) != 1
+	}
+
+	if path := FindPath(Hex{}, goal, walkable); path != nil {
+		t.Errorf("FindPath() = %v, want nil", path)
+	}
+	if path, cost := FindWeightedPath(Hex{}, goal, walkable, uniformCost, 1); path != nil || cost != 0 {
+		t.Errorf("FindWeightedPath() = %v, %d, want nil, 0", path, cost)
+	}
+}
+
+// TestReachable verifies the movement range flood fill respects the budget,
+// terrain costs and unit occupancy
+func TestReachable(t *testing.T) {
+	walkable := func(h Hex) bool { return HexDistance(Hex{}, h) <= 5 }
+	occupants := map[Hex]Occupant{{1, 0}: Ally, {-1, 0}: Enemy}
+	occupant := func(h Hex) Occupant { return occupants[h] }
+
+	reach := Reachable(Hex{}, 2, walkable, uniformCost, occupant)
+
+	if _, ok := reach.CostTo(Hex{-1, 0}); ok {
+		t.Error("enemy hex should not be reachable")
+	}
+	if node := reach.Nodes[Hex{1, 0}]; node.CanStop {
+		t.Error("ally hex should not be a valid destination")
+	}
+	if cost, ok := reach.CostTo(Hex{2, 0}); !ok || cost != 2 {
+		t.Errorf("CostTo(2,0) = %d, %v, want 2, true", cost, ok)
+	}
+	if _, ok := reach.CostTo(Hex{-2, 0}); ok {
+		t.Error("(-2,0) is only reachable around the enemy for 3 movement")
+	}
+
+	for _, h := range reach.Destinations() {
+		if h == (Hex{1, 0}) {
+			t.Error("Destinations() includes ally hex")
+		}
+		if HexDistance(Hex{}, h) > 2 {
+			t.Errorf("Destinations() includes out of range hex %v", h)
+		}
+	}
+
+	path := reach.PathTo(Hex{2, 0})
+	if len(path) != 3 || path[0] != (Hex{}) || path[2] != (Hex{2, 0}) {
+		t.Errorf("PathTo(2,0) = %v", path)
+	}
+	if reach.PathTo(Hex{4, 0}) != nil {
+		t.Error("PathTo out of range hex should be nil")
+	}
+}
+
+// TestReachableMatchesFindPath verifies every reachable hex costs the same as
+// a dedicated path search
+func TestReachableMatchesFindPath(t *testing.T) {
+	walkable := func(h Hex) bool { return HexDistance(Hex{}, h) <= 4 && h != (Hex{1, 1}) }
+	cost := HexCost(func(h Hex) int { return 1 + int(abs(h.Q))%3 })
+
+	reach := Reachable(Hex{}, 6, walkable, cost, nil)
+	for h, node := range reach.Nodes {
+		_, want := FindWeightedPath(Hex{}, h, walkable, cost, 1)
+		if node.Cost != want {
+			t.Errorf("Reachable cost to %v = %d, FindWeightedPath = %d", h, node.Cost, want)
+		}
+	}
+}
+
+// TestHexLine verifies lines are contiguous, have the right length and visit
+// the same hexes whichever end they are cast from
+func TestHexLine(t *testing.T) {
+	origin := Hex{0, 0}
+	for q := int64(-6); q <= 6; q++ {
+		for r := int64(-6); r <= 6; r++ {
+			target := Hex{q, r}
+			line := HexLine(origin, target)
+
+			if int64(len(line)) != HexDistance(origin, target)+1 {
+				t.Fatalf("HexLine(%v, %v) has %d hexes, want %d", origin, target, len(line), HexDistance(origin, target)+1)
+			}
+			if line[0] != origin || line[len(line)-1] != target {
+				t.Fatalf("HexLine(%v, %v) endpoints = %v, %v", origin, target, line[0], line[len(line)-1])
+			}
+			for i := 1; i < len(line); i++ {
+				if HexDistance(line[i-1], line[i]) != 1 {
+					t.Fatalf("HexLine(%v, %v) skips from %v to %v", origin, target, line[i-1], line[i])
+				}
+			}
+
+			reverse := HexLine(target, origin)
+			for i := range line {
+				if line[i] != reverse[len(reverse)-1-i] {
+					t.Fatalf("HexLine(%v, %v) = %v, reversed = %v", origin, target, line, reverse)
+				}
+			}
+		}
+	}
+}
+
+// TestHexLineKnown checks a few lines against hand-computed results
+func TestHexLineKnown(t *testing.T) {
+	tests := []struct {
+		from, to Hex
+		want     []Hex
+	}{
+		{Hex{0, 0}, Hex{0, 0}, []Hex{{0, 0}}},
+		{Hex{0, 0}, Hex{3, 0}, []Hex{{0, 0}, {1, 0}, {2, 0}, {3, 0}}},
+		{Hex{0, 0}, Hex{-3, 0}, []Hex{{0, 0}, {-1, 0}, {-2, 0}, {-3, 0}}},
+		{Hex{0, 0}, Hex{-2, 2}, []Hex{{0, 0}, {-1, 1}, {-2, 2}}},
+		// Passes exactly between (1,-1) and (1,0); the nudge picks (1,0)
+		{Hex{0, 0}, Hex{2, -1}, []Hex{{0, 0}, {1, 0}, {2, -1}}},
+	}
+
+	for _, tt := range tests {
+		got := HexLine(tt.from, tt.to)
+		if len(got) != len(tt.want) {
+			t.Errorf("HexLine(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("HexLine(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestLineOfSightSymmetric verifies LOS gives the same answer from both ends
+// and that edge-grazing lines are only blocked by walls on both sides
+func TestLineOfSightSymmetric(t *testing.T) {
+	walls := map[Hex]bool{{1, 0}: true, {2, 1}: true, {-1, 2}: true, {0, -2}: true, {-2, 0}: true}
+	isBlocking := func(h Hex) bool { return walls[h] }
+
+	hexes := []Hex{}
+	for q := int64(-4); q <= 4; q++ {
+		for r := int64(-4); r <= 4; r++ {
+			hexes = append(hexes, Hex{q, r})
+		}
+	}
+
+	for _, mode := range []LineOfSightMode{LineOfSightNudged, LineOfSightSymmetric} {
+		for _, a := range hexes {
+			for _, b := range hexes {
+				if LineOfSight(a, b, isBlocking, mode) != LineOfSight(b, a, isBlocking, mode) {
+					t.Fatalf("mode %d: LOS(%v,%v) != LOS(%v,%v)", mode, a, b, b, a)
+				}
+			}
+		}
+	}
+
+	// (0,0)->(2,-1) runs along the edge between (1,-1) and (1,0)
+	oneSide := func(h Hex) bool { return h == (Hex{1, 0}) }
+	if !HasLineOfSight(Hex{0, 0}, Hex{2, -1}, oneSide) {
+		t.Error("a wall on one side of the edge should not block symmetric LOS")
+	}
+	if LineOfSight(Hex{0, 0}, Hex{2, -1}, oneSide, LineOfSightNudged) {
+		t.Error("nudged LOS should resolve the tie into the wall")
+	}
+	bothSides := func(h Hex) bool { return h == (Hex{1, 0}) || h == (Hex{1, -1}) }
+	if HasLineOfSight(Hex{0, 0}, Hex{2, -1}, bothSides) {
+		t.Error("walls on both sides of the edge should block LOS")
+	}
+}
+
+// visibleHexesByLineOfSight is the original per-target implementation of
+// GetVisibleHexes, kept as a reference for the shadowcasting field of view
+func visibleHexesByLineOfSight(origin Hex, maxRange int64, isBlocking func(Hex) bool) []Hex {
+	visibleHexes := []Hex{}
+
+	for q := origin.Q - maxRange; q <= origin.Q+maxRange; q++ {
+		for r := origin.R - maxRange; r <= origin.R+maxRange; r++ {
+			target := Hex{Q: q, R: r}
+			if HexDistance(origin, target) > maxRange {
+				continue
+			}
+
+			if HasLineOfSight(origin, target, isBlocking) {
+				visibleHexes = append(visibleHexes, target)
+			}
+		}
+	}
+
+	return visibleHexes
+}
+
+func toSet(hexes []Hex) map[Hex]bool {
+	set := make(map[Hex]bool, len(hexes))
+	for _, h := range hexes {
+		set[h] = true
+	}
+	return set
+}
+
+// TestFieldOfViewOpen verifies an unobstructed field of view contains every
+// hex in range under all occlusion rules
+func TestFieldOfViewOpen(t *testing.T) {
+	noWalls := func(Hex) bool { return false }
+	want := toSet(visibleHexesByLineOfSight(Hex{2, -1}, 10, noWalls))
+
+	for _, occlusion := range []Occlusion{OcclusionCenter, OcclusionPartial, OcclusionFull} {
+		got := FieldOfView(Hex{2, -1}, 10, noWalls, occlusion)
+		if len(got) != 331 || len(toSet(got)) != len(want) {
+			t.Errorf("occlusion %d: got %d hexes, want 331", occlusion, len(got))
+		}
+		for _, h := range got {
+			if !want[h] {
+				t.Errorf("occlusion %d: unexpected hex %v", occlusion, h)
+			}
+		}
+	}
+}
+
+// TestFieldOfViewMatchesLineOfSight verifies shadowcasting brackets the
+// per-target line of sight reference: the strict rule never shows more and
+// the permissive rule never shows less
+func TestFieldOfViewMatchesLineOfSight(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for trial := 0; trial < 50; trial++ {
+		walls := map[Hex]bool{}
+		for i := 0; i < 40; i++ {
+			walls[Hex{rng.Int64N(17) - 8, rng.Int64N(17) - 8}] = true
+		}
+		delete(walls, Hex{})
+		isBlocking := func(h Hex) bool { return walls[h] }
+
+		reference := toSet(visibleHexesByLineOfSight(Hex{}, 8, isBlocking))
+		full := toSet(FieldOfView(Hex{}, 8, isBlocking, OcclusionFull))
+		center := toSet(FieldOfView(Hex{}, 8, isBlocking, OcclusionCenter))
+		partial := toSet(FieldOfView(Hex{}, 8, isBlocking, OcclusionPartial))
+
+		for h := range full {
+			if !reference[h] || !center[h] {
+				t.Errorf("trial %d: OcclusionFull shows %v", trial, h)
+			}
+		}
+		for h := range reference {
+			if !partial[h] {
+				t.Errorf("trial %d: OcclusionPartial hides %v", trial, h)
+			}
+		}
+		for h := range center {
+			if !partial[h] {
+				t.Errorf("trial %d: OcclusionCenter shows %v outside OcclusionPartial", trial, h)
+			}
+		}
+	}
+}
+
+// TestGetVisibleHexesWalls verifies walls are visible but hide what is behind them
+func TestGetVisibleHexesWalls(t *testing.T) {
+	// A closed ring of walls two steps out
+	walls := toSet(Ring(Hex{}, 2))
+	visible := toSet(GetVisibleHexes(Hex{}, 6, func(h Hex) bool { return walls[h] }))
+
+	if len(visible) != 19 {
+		t.Errorf("got %d visible hexes, want 19", len(visible))
+	}
+	for h := range visible {
+		if HexDistance(Hex{}, h) > 2 {
+			t.Errorf("hex %v behind the wall is visible", h)
+		}
+	}
+
+	// A single wall hides the hex straight behind it
+	single := func(h Hex) bool { return h == (Hex{1, 0}) }
+	visible = toSet(GetVisibleHexes(Hex{}, 3, single))
+	if !visible[Hex{1, 0}] {
+		t.Error("the wall itself should be visible")
+	}
+	if visible[Hex{2, 0}] || visible[Hex{3, 0}] {
+		t.Error("hexes directly behind the wall should be hidden")
+	}
+	if !visible[Hex{2, -1}] {
+		t.Error("a single wall should not hide the edge-grazing hex (2,-1)")
+	}
+}
+
+// TestMapShapes verifies each constructor builds the expected number of hexes
+func TestMapShapes(t *testing.T) {
+	tests := []struct {
+		name  string
+		hexes []Hex
+		want  int
+	}{
+		{"hexagon radius 3", NewHexagonalMap[int](3).Hexes(), 37},
+		{"rectangle 4x3", NewRectangularMap[int](4, 3).Hexes(), 12},
+		{"parallelogram 11x11", NewParallelogramMap[int](-5, 5, -5, 5).Hexes(), 121},
+		{"triangle size 3", NewTriangularMap[int](3).Hexes(), 10},
+		{"custom with duplicates", NewMap[int]([]Hex{{0, 0}, {1, 0}, {0, 0}}).Hexes(), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.hexes) != tt.want {
+				t.Errorf("got %d hexes, want %d", len(tt.hexes), tt.want)
+			}
+		})
+	}
+
+	for _, h := range NewHexagonalMap[int](3).Hexes() {
+		if HexDistance(Hex{}, h) > 3 {
+			t.Errorf("hexagon contains %v outside radius 3", h)
+		}
+	}
+
+	// Every column of a rectangle spans the same screen rows
+	layout := NewLayout()
+	rect := NewRectangularMap[int](4, 3)
+	_, top := layout.HexToPixel(0, 0)
+	for _, h := range rect.Hexes() {
+		_, y := layout.HexToPixel(h.Q, h.R)
+		if y < top-1e-9 {
+			t.Errorf("rectangle hex %v is above the first row", h)
+		}
+	}
+}
+
+// TestMapGetSet verifies values only live on hexes inside the shape
+func TestMapGetSet(t *testing.T) {
+	m := NewHexagonalMap[string](1)
+
+	if !m.Set(Hex{1, -1}, "rock") {
+		t.Error("Set inside the map should succeed")
+	}
+	if m.Set(Hex{2, 0}, "rock") {
+		t.Error("Set outside the map should fail")
+	}
+	if v, ok := m.Get(Hex{1, -1}); !ok || v != "rock" {
+		t.Errorf("Get(1,-1) = %q, %v", v, ok)
+	}
+	if _, ok := m.Get(Hex{2, 0}); ok {
+		t.Error("Get outside the map should fail")
+	}
+
+	m.Fill(func(h Hex) string { return "grass" })
+	for h, v := range m.All() {
+		if v != "grass" {
+			t.Errorf("%v = %q after Fill", h, v)
+		}
+	}
+}
+
+// TestMapDrawOrder verifies iteration runs back to front on screen
+func TestMapDrawOrder(t *testing.T) {
+	layout := NewLayout()
+	lastY := math.Inf(-1)
+	for h := range NewHexagonalMap[int](4).All() {
+		_, y := layout.HexToPixel(h.Q, h.R)
+		if y < lastY-1e-9 {
+			t.Fatalf("%v drawn after a hex lower on screen", h)
+		}
+		lastY = y
+	}
+}
+
+// TestMapNeighborsAndPathing verifies neighbor lookup and walkability stay
+// inside the shape
+func TestMapNeighborsAndPathing(t *testing.T) {
+	m := NewTriangularMap[bool](4)
+	if got := len(m.Neighbors(Hex{0, 0})); got != 2 {
+		t.Errorf("corner has %d neighbors, want 2", got)
+	}
+	if got := len(m.Neighbors(Hex{1, 1})); got != 6 {
+		t.Errorf("interior hex has %d neighbors, want 6", got)
+	}
+
+	m.Fill(func(Hex) bool { return true })
+	m.Set(Hex{1, 0}, false)
+	m.Set(Hex{1, 1}, false)
+	m.Set(Hex{0, 1}, false)
+
+	if path := FindPath(Hex{0, 0}, Hex{4, 0}, m.Walkable(func(open bool) bool { return open })); path != nil {
+		t.Errorf("corner is walled in but found path %v", path)
+	}
+	if path := FindPath(Hex{0, 0}, Hex{4, 0}, m.Contains); len(path) != 5 {
+		t.Errorf("FindPath over the whole map = %v", path)
+	}
+}
+
+// bruteForceRange filters a bounding square by distance, the way the range
+// functions replace
+func bruteForceRange(center Hex, n int64) map[Hex]bool {
+	set := map[Hex]bool{}
+	for q := center.Q - n; q <= center.Q+n; q++ {
+		for r := center.R - n; r <= center.R+n; r++ {
+			if h := (Hex{q, r}); HexDistance(center, h) <= n {
+				set[h] = true
+			}
+		}
+	}
+	return set
+}
+
+// TestRingAndSpiral verifies ring sizes, distances and spiral ordering
+func TestRingAndSpiral(t *testing.T) {
+	center := Hex{2, -3}
+	for radius := int64(0); radius <= 5; radius++ {
+		ringHexes := Ring(center, radius)
+		want := max(1, 6*radius)
+		if int64(len(toSet(ringHexes))) != want {
+			t.Errorf("Ring(%d) has %d distinct hexes, want %d", radius, len(toSet(ringHexes)), want)
+		}
+		for i, h := range ringHexes {
+			if HexDistance(center, h) != radius {
+				t.Errorf("Ring(%d) contains %v at distance %d", radius, h, HexDistance(center, h))
+			}
+			if next := ringHexes[(i+1)%len(ringHexes)]; radius > 0 && HexDistance(h, next) != 1 {
+				t.Errorf("Ring(%d) jumps from %v to %v", radius, h, next)
+			}
+		}
+	}
+
+	spiral := Spiral(center, 4)
+	if len(spiral) != 61 || len(toSet(spiral)) != 61 {
+		t.Errorf("Spiral(4) has %d hexes, want 61 distinct", len(spiral))
+	}
+	for i := 1; i < len(spiral); i++ {
+		if HexDistance(center, spiral[i]) < HexDistance(center, spiral[i-1]) {
+			t.Fatalf("Spiral goes back inward at %v", spiral[i])
+		}
+	}
+}
+
+// TestRangeMatchesBruteForce verifies Range and IntersectRanges against a
+// filtered bounding box
+func TestRangeMatchesBruteForce(t *testing.T) {
+	for n := int64(0); n <= 4; n++ {
+		got := toSet(Range(Hex{1, 1}, n))
+		want := bruteForceRange(Hex{1, 1}, n)
+		if len(got) != len(want) {
+			t.Errorf("Range(%d) has %d hexes, want %d", n, len(got), len(want))
+		}
+		for h := range want {
+			if !got[h] {
+				t.Errorf("Range(%d) is missing %v", n, h)
+			}
+		}
+	}
+
+	tests := []struct {
+		a, b   Hex
+		ra, rb int64
+	}{
+		{Hex{0, 0}, Hex{3, 0}, 2, 2},
+		{Hex{0, 0}, Hex{2, -4}, 3, 4},
+		{Hex{-1, 2}, Hex{-1, 2}, 1, 3},
+		{Hex{0, 0}, Hex{5, 0}, 2, 2},
+	}
+	for _, tt := range tests {
+		got := toSet(IntersectRanges(tt.a, tt.ra, tt.b, tt.rb))
+		inB := bruteForceRange(tt.b, tt.rb)
+		want := 0
+		for h := range bruteForceRange(tt.a, tt.ra) {
+			if inB[h] {
+				want++
+				if !got[h] {
+					t.Errorf("IntersectRanges(%v,%d,%v,%d) is missing %v", tt.a, tt.ra, tt.b, tt.rb, h)
+				}
+			}
+		}
+		if len(got) != want {
+			t.Errorf("IntersectRanges(%v,%d,%v,%d) has %d hexes, want %d", tt.a, tt.ra, tt.b, tt.rb, len(got), want)
+		}
+	}
+}
+
+// TestRangeWithObstacles verifies walls shorten the reachable range
+func TestRangeWithObstacles(t *testing.T) {
+	if got := len(RangeWithObstacles(Hex{}, 2, func(Hex) bool { return false })); got != 19 {
+		t.Errorf("open range has %d hexes, want 19", got)
+	}
+
+	// Wall off every neighbor but (1,0)
+	open := Hex{1, 0}
+	isBlocking := func(h Hex) bool { return HexDistance(Hex{}, h) == 1 && h != open }
+	got := toSet(RangeWithObstacles(Hex{}, 2, isBlocking))
+	for h := range got {
+		if isBlocking(h) {
+			t.Errorf("range contains blocked hex %v", h)
+		}
+		if h != (Hex{}) && h != open && HexDistance(open, h) != 1 {
+			t.Errorf("%v is not reachable in 2 steps through the gap", h)
+		}
+	}
+	if len(got) != 5 {
+		t.Errorf("range through the gap has %d hexes, want 5", len(got))
+	}
+}
+
+// TestDirections verifies direction offsets, rotation and snapping
+func TestDirections(t *testing.T) {
+	for d := Direction(0); d < 6; d++ {
+		if HexDistance(Hex{}, d.Offset()) != 1 {
+			t.Errorf("direction %d offset %v is not a neighbor", d, d.Offset())
+		}
+		back := Neighbor(Neighbor(Hex{}, d), d.Opposite())
+		if back != (Hex{}) {
+			t.Errorf("direction %d and its opposite do not cancel", d)
+		}
+		if d.Rotate(6) != d || d.Rotate(-1) != d.Rotate(5) {
+			t.Errorf("direction %d rotation does not wrap", d)
+		}
+		far := Hex{Q: d.Offset().Q * 5, R: d.Offset().R * 5}
+		if got := DirectionTowards(Hex{}, far); got != d {
+			t.Errorf("DirectionTowards(%v) = %d, want %d", far, got, d)
+		}
+		// Slightly off axis still snaps to the same direction
+		nudged := Neighbor(far, d.Rotate(1))
+		if got := DirectionTowards(Hex{}, nudged); got != d {
+			t.Errorf("DirectionTowards(%v) = %d, want %d", nudged, got, d)
+		}
+	}
+}
+
+// TestDirectionTowardsTies verifies targets exactly between two directions
+// go to the lower-numbered one, however far away they are
+func TestDirectionTowardsTies(t *testing.T) {
+	tests := []struct {
+		to   Hex
+		want Direction
+	}{
+		{Hex{2, -1}, 0},
+		{Hex{1, -2}, 1},
+		{Hex{-1, -1}, 2},
+		{Hex{-2, 1}, 3},
+		{Hex{-1, 2}, 4},
+		{Hex{1, 1}, 0},
+	}
+	for _, tt := range tests {
+		for _, scale := range []int64{1, 3, 7} {
+			to := tt.to.Scale(scale)
+			if got := DirectionTowards(Hex{}, to); got != tt.want {
+				t.Errorf("DirectionTowards(%v) = %d, want %d", to, got, tt.want)
+			}
+			from := Hex{4, -9}
+			if got := DirectionTowards(from, from.Add(to)); got != tt.want {
+				t.Errorf("DirectionTowards(%v, %v) = %d, want %d", from, from.Add(to), got, tt.want)
+			}
+		}
+	}
+}
+
+// TestFootprintPath verifies a large entity only moves where its whole body fits
+func TestFootprintPath(t *testing.T) {
+	boss := HexagonFootprint(1)
+	if len(boss) != 7 {
+		t.Fatalf("radius 1 footprint has %d hexes, want 7", len(boss))
+	}
+
+	// Two open rooms joined by a corridor one hex wide
+	open := map[Hex]bool{}
+	for _, h := range Range(Hex{-6, 0}, 3) {
+		open[h] = true
+	}
+	for _, h := range Range(Hex{6, 0}, 3) {
+		open[h] = true
+	}
+	for q := int64(-3); q <= 3; q++ {
+		open[Hex{q, 0}] = true
+	}
+	isWalkable := func(h Hex) bool { return open[h] }
+
+	if path := FindPath(Hex{-6, 0}, Hex{6, 0}, isWalkable); path == nil {
+		t.Fatal("a single hex unit should fit through the corridor")
+	}
+	if path, _ := FindFootprintPath(Hex{-6, 0}, Hex{6, 0}, boss, isWalkable, uniformCost, 1); path != nil {
+		t.Errorf("boss squeezed through a one hex corridor: %v", path)
+	}
+
+	// Widen the corridor to three hexes
+	for q := int64(-3); q <= 3; q++ {
+		open[Hex{q, -1}] = true
+		open[Hex{q, 1}] = true
+		open[Hex{q + 1, -1}] = true
+		open[Hex{q - 1, 1}] = true
+	}
+	path, _ := FindFootprintPath(Hex{-6, 0}, Hex{6, 0}, boss, isWalkable, uniformCost, 1)
+	if path == nil {
+		t.Fatal("boss should fit through a three hex corridor")
+	}
+	for _, anchor := range path {
+		if !boss.Fits(anchor, isWalkable) {
+			t.Errorf("boss does not fit at %v", anchor)
+		}
+	}
+}
+
+// TestFootprintAdjacency verifies melee reach against every hex of a footprint
+func TestFootprintAdjacency(t *testing.T) {
+	boss := HexagonFootprint(1)
+	anchor := Hex{2, 2}
+
+	if !boss.Covers(anchor, Hex{3, 2}) || boss.Covers(anchor, Hex{4, 2}) {
+		t.Error("Covers does not match the radius 1 body")
+	}
+	if !boss.IsAdjacent(anchor, Hex{4, 2}) {
+		t.Error("(4,2) touches the boss")
+	}
+	if boss.IsAdjacent(anchor, Hex{3, 2}) {
+		t.Error("a hex inside the boss is not adjacent to it")
+	}
+	if boss.IsAdjacent(anchor, Hex{5, 2}) {
+		t.Error("(5,2) is two hexes from the boss")
+	}
+	if d := boss.Distance(anchor, Hex{6, 2}); d != 3 {
+		t.Errorf("Distance to (6,2) = %d, want 3", d)
+	}
+}
+
+// TestReachableFootprint verifies occupancy is checked under the whole body
+func TestReachableFootprint(t *testing.T) {
+	boss := HexagonFootprint(1)
+	isWalkable := func(h Hex) bool { return HexDistance(Hex{}, h) <= 6 }
+	occupant := func(h Hex) Occupant {
+		if h == (Hex{3, 0}) {
+			return Enemy
+		}
+		return Unoccupied
+	}
+
+	reach := ReachableFootprint(Hex{}, 3, boss, isWalkable, uniformCost, occupant)
+	if _, ok := reach.CostTo(Hex{2, 0}); ok {
+		t.Error("the boss cannot move onto a spot overlapping an enemy")
+	}
+	for anchor := range reach.Nodes {
+		if !boss.Fits(anchor, isWalkable) {
+			t.Errorf("reach includes %v where the body leaves the map", anchor)
+		}
+	}
+	if cost, ok := reach.CostTo(Hex{0, 2}); !ok || cost != 2 {
+		t.Errorf("CostTo(0,2) = %d, %v, want 2, true", cost, ok)
+	}
+}
+
+// TestEdgeCanonical verifies each border between two hexes has one Edge value
+func TestEdgeCanonical(t *testing.T) {
+	for _, h := range Range(Hex{1, -2}, 2) {
+		for d := Direction(0); d < 6; d++ {
+			n := Neighbor(h, d)
+			ab, ok := EdgeBetween(h, n)
+			ba, _ := EdgeBetween(n, h)
+			if !ok || ab != ba || ab != EdgeOf(h, d) || ab != EdgeOf(n, d.Opposite()) {
+				t.Fatalf("edge between %v and %v is not canonical: %v %v", h, n, ab, ba)
+			}
+			if ab.Side < 0 || ab.Side > 2 {
+				t.Errorf("edge %v has side %d", ab, ab.Side)
+			}
+			x, y := ab.Hexes()
+			if !(x == h && y == n) && !(x == n && y == h) {
+				t.Errorf("edge %v separates %v and %v, want %v and %v", ab, x, y, h, n)
+			}
+		}
+	}
+
+	if _, ok := EdgeBetween(Hex{0, 0}, Hex{2, 0}); ok {
+		t.Error("hexes two apart share no edge")
+	}
+}
+
+// TestVertexCanonical verifies the three hexes at a corner agree on it
+func TestVertexCanonical(t *testing.T) {
+	for _, h := range Range(Hex{}, 2) {
+		for corner := 0; corner < 6; corner++ {
+			v := VertexOf(h, corner)
+			if v.Corner != 0 && v.Corner != 1 {
+				t.Fatalf("VertexOf(%v, %d) = %v", h, corner, v)
+			}
+
+			hexes := v.Hexes()
+			found := false
+			for _, touching := range hexes {
+				if touching == h {
+					found = true
+				}
+				// The same vertex is one of the six corners of every touching hex
+				matches := 0
+				for k := 0; k < 6; k++ {
+					if VertexOf(touching, k) == v {
+						matches++
+					}
+				}
+				if matches != 1 {
+					t.Errorf("%v is corner of %v %d times", v, touching, matches)
+				}
+			}
+			if !found {
+				t.Errorf("VertexOf(%v, %d) = %v does not touch %v", h, corner, v, h)
+			}
+			for _, e := range v.Edges() {
+				a, b := e.Vertices()
+				if a != v && b != v {
+					t.Errorf("edge %v does not end at %v", e, v)
+				}
+			}
+		}
+	}
+}
+
+// TestVertexPixels verifies corners and edges line up with GetCorners in
+// both orientations
+func TestVertexPixels(t *testing.T) {
+	for _, orientation := range []Orientation{Flat, Pointy} {
+		config := DefaultLayoutConfig()
+		config.Orientation = orientation
+		layout := NewLayoutWithConfig(config)
+
+		for _, h := range Range(Hex{}, 1) {
+			corners := layout.GetCorners(h.Q, h.R)
+			for k := 0; k < 6; k++ {
+				x, y := layout.VertexToPixel(VertexOf(h, k))
+				found := false
+				for _, c := range corners {
+					if math.Abs(float64(c.DstX)-x) < 1e-3 && math.Abs(float64(c.DstY)-y) < 1e-3 {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("orientation %d: corner %d of %v at (%f,%f) is not a drawn corner", orientation, k, h, x, y)
+				}
+			}
+
+			// An edge's midpoint lies halfway between the two hex centers
+			for d := Direction(0); d < 6; d++ {
+				x1, y1, x2, y2 := layout.EdgeToPixels(EdgeOf(h, d))
+				n := Neighbor(h, d)
+				ax, ay := layout.HexToPixel(h.Q, h.R)
+				bx, by := layout.HexToPixel(n.Q, n.R)
+				if math.Abs((x1+x2)-(ax+bx)) > 1e-6 || math.Abs((y1+y2)-(ay+by)) > 1e-6 {
+					t.Errorf("orientation %d: edge %d of %v is not between the two centers", orientation, d, h)
+				}
+			}
+		}
+	}
+}
+
+// TestEdgeBlocking verifies walls on borders stop paths and sight
+func TestEdgeBlocking(t *testing.T) {
+	walls := map[Edge]bool{}
+	// Wall off the whole border between q=0 and q=1 for r in [-3,3]
+	for r := int64(-3); r <= 3; r++ {
+		e1, _ := EdgeBetween(Hex{0, r}, Hex{1, r})
+		e2, _ := EdgeBetween(Hex{0, r}, Hex{1, r - 1})
+		walls[e1], walls[e2] = true, true
+	}
+	isEdgeBlocked := func(e Edge) bool { return walls[e] }
+	isWalkable := func(h Hex) bool { return HexDistance(Hex{}, h) <= 5 }
+
+	direct := FindPath(Hex{0, 0}, Hex{1, 0}, isWalkable)
+	if len(direct) != 2 {
+		t.Fatalf("without walls the path is one step, got %v", direct)
+	}
+	path, cost := FindPathWithEdges(Hex{0, 0}, Hex{1, 0}, isWalkable, isEdgeBlocked, uniformCost, 1)
+	if path == nil || cost <= 1 {
+		t.Fatalf("path through the wall = %v, cost %d", path, cost)
+	}
+	for i := 1; i < len(path); i++ {
+		if e, _ := EdgeBetween(path[i-1], path[i]); walls[e] {
+			t.Errorf("path crosses the wall between %v and %v", path[i-1], path[i])
+		}
+	}
+
+	noHexWalls := func(Hex) bool { return false }
+	if HasLineOfSightWithEdges(Hex{-1, 0}, Hex{2, 0}, noHexWalls, isEdgeBlocked) {
+		t.Error("sight line crosses a wall edge")
+	}
+	if !HasLineOfSightWithEdges(Hex{-1, 0}, Hex{0, 2}, noHexWalls, isEdgeBlocked) {
+		t.Error("sight line on one side of the wall should be clear")
+	}
+}
+
+// TestCoverFromElevation verifies low walls give partial cover, high ground
+// sees over them and taller obstacles shade further
+func TestCoverFromElevation(t *testing.T) {
+	heights := map[Hex]float64{}
+	height := func(h Hex) float64 { return heights[h] }
+
+	if c := CoverBetween(Hex{0, 0}, Hex{4, 0}, height); c != CoverNone {
+		t.Errorf("flat ground gives %v cover", c)
+	}
+
+	tests := []struct {
+		wall float64
+		want Cover
+	}{
+		{0.2, CoverNone},
+		{0.5, CoverHalf},
+		{0.8, CoverThreeQuarters},
+		{1.5, CoverFull},
+	}
+	for _, tt := range tests {
+		// A wall right in front of the target
+		heights[Hex{3, 0}] = tt.wall
+		if got := CoverBetween(Hex{0, 0}, Hex{4, 0}, height); got != tt.want {
+			t.Errorf("wall of height %.1f gives %v cover, want %v", tt.wall, got, tt.want)
+		}
+	}
+
+	// A wall next to the attacker blocks everything from the ground...
+	heights = map[Hex]float64{{1, 0}: 1}
+	if got := CoverBetween(Hex{0, 0}, Hex{4, 0}, height); got != CoverFull {
+		t.Errorf("from the ground: %v cover, want full", got)
+	}
+	// ...but not from a raised hex
+	heights[Hex{0, 0}] = 3
+	if got := CoverBetween(Hex{0, 0}, Hex{4, 0}, height); got != CoverNone {
+		t.Errorf("from high ground: %v cover, want none", got)
+	}
+
+	shadow := func(wall float64) int {
+		heights = map[Hex]float64{{0, 0}: 3, {1, 0}: wall}
+		hidden := 0
+		for q := int64(2); q <= 8; q++ {
+			if !HasElevatedLineOfSight(Hex{0, 0}, Hex{q, 0}, height) {
+				hidden++
+			}
+		}
+		return hidden
+	}
+	if low, high := shadow(2), shadow(3); high <= low {
+		t.Errorf("a taller wall hides %d hexes, a lower one %d", high, low)
+	}
+}
+
+// TestCoverBonuses verifies cover converts to 5e armor class bonuses
+func TestCoverBonuses(t *testing.T) {
+	tests := []struct {
+		cover      Cover
+		bonus      int
+		targetable bool
+	}{
+		{CoverNone, 0, true},
+		{CoverHalf, 2, true},
+		{CoverThreeQuarters, 5, true},
+		{CoverFull, 0, false},
+	}
+	for _, tt := range tests {
+		if tt.cover.ACBonus() != tt.bonus || tt.cover.Targetable() != tt.targetable {
+			t.Errorf("%v: bonus %d targetable %v", tt.cover, tt.cover.ACBonus(), tt.cover.Targetable())
+		}
+	}
+}
+
+// TestPickHexWithElevation verifies the cursor picks the raised hex drawn on
+// top rather than the flat-plane hex under it
+func TestPickHexWithElevation(t *testing.T) {
+	layout := NewLayout()
+	board := NewHexagonalMap[float64](4)
+	board.Set(Hex{0, 1}, 2)
+	height := func(h Hex) float64 {
+		v, _ := board.Get(h)
+		return v
+	}
+
+	// Flat picking still works away from the raised hex
+	for _, h := range []Hex{{-2, 0}, {3, -3}, {0, -1}} {
+		x, y := layout.HexToPixel(h.Q, h.R)
+		if got, ok := layout.PickHex(x, y, board.Contains, height, 2); !ok || got != h {
+			t.Errorf("PickHex at %v = %v, %v", h, got, ok)
+		}
+	}
+
+	// The top of the raised hex is drawn where a hex further back sits on
+	// the flat plane
+	x, y := layout.HexToPixelAt(0, 1, 2)
+	flatQ, flatR := layout.PixelToHex(x, y)
+	if flatQ == 0 && flatR == 1 {
+		t.Fatal("test needs the raised hex to overlap another hex")
+	}
+	if got, ok := layout.PickHex(x, y, board.Contains, height, 2); !ok || got != (Hex{0, 1}) {
+		t.Errorf("PickHex on raised top = %v, %v, want (0,1)", got, ok)
+	}
+
+	// Its front wall hides part of the ground hex in front of it
+	wallX, wallY := layout.HexToPixelAt(0, 1, 1)
+	wallY += layout.Size() * DefaultIsoScaleY * 0.8
+	if got, _ := layout.PickHex(wallX, wallY, board.Contains, height, 2); got != (Hex{0, 1}) {
+		t.Errorf("PickHex on the wall = %v, want (0,1)", got)
+	}
+
+	if _, ok := layout.PickHex(0, 0, board.Contains, height, 2); ok {
+		t.Error("PickHex off the map should fail")
+	}
+}
+
+// TestSideWallsAndPaintersOrder verifies only front-facing drops get walls
+// and hexes are painted back to front
+func TestSideWallsAndPaintersOrder(t *testing.T) {
+	layout := NewLayout()
+	heights := map[Hex]float64{{0, 0}: 1}
+	height := func(h Hex) float64 { return heights[h] }
+
+	walls := layout.SideWalls(Hex{0, 0}, height)
+	if len(walls) != 3 {
+		t.Errorf("a lone raised hex shows %d walls, want 3", len(walls))
+	}
+	for _, wall := range walls {
+		if len(wall) != 4 || wall[0].DstY >= wall[3].DstY {
+			t.Errorf("wall %v does not hang down from the top face", wall)
+		}
+	}
+	if len(layout.SideWalls(Hex{1, 0}, height)) != 0 {
+		t.Error("a ground hex should have no walls")
+	}
+
+	lastY := math.Inf(-1)
+	for h := range layout.PaintersOrder(Range(Hex{}, 3)) {
+		_, y := layout.HexToPixel(h.Q, h.R)
+		if y < lastY-1e-9 {
+			t.Fatalf("%v painted after a hex in front of it", h)
+		}
+		lastY = y
+	}
+}
+
+// TestOffsetRoundTrip verifies every offset system converts to axial and back
+// without loss, and puts its shoved columns or rows on the right side
+func TestOffsetRoundTrip(t *testing.T) {
+	for _, system := range []OffsetSystem{OddQ, EvenQ, OddR, EvenR} {
+		for _, h := range Range(Hex{}, 6) {
+			if got := FromOffset(ToOffset(h, system), system); got != h {
+				t.Errorf("system %d: %v round tripped to %v", system, h, got)
+			}
+		}
+		for col := int64(-5); col <= 5; col++ {
+			for row := int64(-5); row <= 5; row++ {
+				c := OffsetCoord{col, row}
+				if got := ToOffset(FromOffset(c, system), system); got != c {
+					t.Errorf("system %d: %v round tripped to %v", system, c, got)
+				}
+			}
+		}
+	}
+
+	// Known values from the common references
+	tests := []struct {
+		system OffsetSystem
+		c      OffsetCoord
+		want   Hex
+	}{
+		{OddQ, OffsetCoord{1, 0}, Hex{1, 0}},
+		{OddQ, OffsetCoord{1, 1}, Hex{1, 1}},
+		{OddQ, OffsetCoord{2, 1}, Hex{2, 0}},
+		{EvenQ, OffsetCoord{1, 1}, Hex{1, 0}},
+		{EvenQ, OffsetCoord{-1, 0}, Hex{-1, 0}},
+		{OddR, OffsetCoord{0, 1}, Hex{0, 1}},
+		{OddR, OffsetCoord{1, 2}, Hex{0, 2}},
+		{EvenR, OffsetCoord{0, 1}, Hex{-1, 1}},
+		{EvenR, OffsetCoord{0, -1}, Hex{0, -1}},
+	}
+	for _, tt := range tests {
+		if got := FromOffset(tt.c, tt.system); got != tt.want {
+			t.Errorf("FromOffset(%v, %d) = %v, want %v", tt.c, tt.system, got, tt.want)
+		}
+	}
+}
+
+// TestOffsetRectangularMapIsScreenAligned verifies each offset rectangle
+// lines up with the screen axes of its own orientation
+func TestOffsetRectangularMapIsScreenAligned(t *testing.T) {
+	for _, system := range []OffsetSystem{OddQ, EvenQ, OddR, EvenR} {
+		config := DefaultLayoutConfig()
+		config.Orientation = system.Orientation()
+		layout := NewLayoutWithConfig(config)
+		board := NewOffsetRectangularMap[int](6, 4, system)
+		if board.Len() != 24 {
+			t.Fatalf("system %d: map has %d hexes, want 24", system, board.Len())
+		}
+
+		// Every hex in a column (or row) sits at that column's x (or row's y)
+		bx, by := layout.HexToPixel(0, 0)
+		size := layout.Size()
+		for h := range board.All() {
+			c := ToOffset(h, system)
+			x, y := layout.HexToPixel(h.Q, h.R)
+			if config.Orientation == Flat {
+				if math.Abs(x-bx-float64(c.Col)*1.5*size) > 1e-9 {
+					t.Errorf("system %d: %v at x %f is off its column", system, c, x)
+				}
+			} else if math.Abs(y-by-float64(c.Row)*1.5*size*DefaultIsoScaleY) > 1e-9 {
+				t.Errorf("system %d: %v at y %f is off its row", system, c, y)
+			}
+		}
+	}
+}
+
+// TestOffsetRectangularMapDrawOrder verifies each offset rectangle iterates
+// top to bottom, then left to right, on screen for its own orientation
+func TestOffsetRectangularMapDrawOrder(t *testing.T) {
+	for _, system := range []OffsetSystem{OddQ, EvenQ, OddR, EvenR} {
+		config := DefaultLayoutConfig()
+		config.Orientation = system.Orientation()
+		layout := NewLayoutWithConfig(config)
+		board := NewOffsetRectangularMap[int](6, 4, system)
+		if board.Orientation() != config.Orientation {
+			t.Errorf("system %d: map orientation %d", system, board.Orientation())
+		}
+
+		var prev Hex
+		first := true
+		for h := range board.All() {
+			if !first {
+				px, py := layout.HexToPixel(prev.Q, prev.R)
+				x, y := layout.HexToPixel(h.Q, h.R)
+				if y < py-1e-9 || math.Abs(y-py) <= 1e-9 && x < px {
+					t.Errorf("system %d: %v iterated after %v but drawn above or left of it", system, h, prev)
+				}
+			}
+			prev, first = h, false
+		}
+	}
+}
+
+// TestDoubledAndCubeRoundTrip verifies the doubled and cube conversions are
+// lossless and reject coordinates no hex has
+func TestDoubledAndCubeRoundTrip(t *testing.T) {
+	for _, h := range Range(Hex{}, 6) {
+		for _, orientation := range []Orientation{Flat, Pointy} {
+			d := ToDoubled(h, orientation)
+			if (d.Col+d.Row)%2 != 0 {
+				t.Errorf("orientation %d: %v doubled to %v with odd parity", orientation, h, d)
+			}
+			if got, ok := FromDoubled(d, orientation); !ok || got != h {
+				t.Errorf("orientation %d: %v round tripped to %v, %v", orientation, h, got, ok)
+			}
+		}
+
+		c := ToCube(h)
+		if c.Q+c.R+c.S != 0 {
+			t.Errorf("cube %v does not sum to zero", c)
+		}
+		if got, ok := FromCube(c); !ok || got != h {
+			t.Errorf("cube %v round tripped to %v, %v", c, got, ok)
+		}
+	}
+
+	// Neighbors in doubled-width coordinates are two columns apart
+	if d := ToDoubled(Neighbor(Hex{}, PointyEast), Pointy); d != (DoubledCoord{2, 0}) {
+		t.Errorf("east neighbor doubled to %v, want (2,0)", d)
+	}
+	if d := ToDoubled(Neighbor(Hex{}, FlatSouth), Flat); d != (DoubledCoord{0, 2}) {
+		t.Errorf("south neighbor doubled to %v, want (0,2)", d)
+	}
+
+	if _, ok := FromDoubled(DoubledCoord{1, 0}, Flat); ok {
+		t.Error("FromDoubled accepted odd parity")
+	}
+	if _, ok := FromCube(Cube{1, 1, 1}); ok {
+		t.Error("FromCube accepted coordinates that do not sum to zero")
+	}
+}
+
+// TestDirectionNamesMatchScreen verifies each compass name points the way the
+// neighbor is drawn in its orientation
+func TestDirectionNamesMatchScreen(t *testing.T) {
+	compass := map[string][2]float64{
+		"north": {0, -1}, "south": {0, 1}, "east": {1, 0}, "west": {-1, 0},
+		"north-east": {1, -1}, "north-west": {-1, -1}, "south-east": {1, 1}, "south-west": {-1, 1},
+	}
+	for _, orientation := range []Orientation{Flat, Pointy} {
+		config := DefaultLayoutConfig()
+		config.Orientation = orientation
+		layout := NewLayoutWithConfig(config)
+		cx, cy := layout.HexToPixel(0, 0)
+
+		for d := Direction(0); d < 6; d++ {
+			n := Neighbor(Hex{}, d)
+			x, y := layout.HexToPixel(n.Q, n.R)
+			want, ok := compass[d.Name(orientation)]
+			if !ok {
+				t.Fatalf("unknown name %q", d.Name(orientation))
+			}
+			if sign(x-cx) != want[0] || sign(y-cy) != want[1] {
+				t.Errorf("orientation %d: %s neighbor drawn at (%f,%f)", orientation, d.Name(orientation), x-cx, y-cy)
+			}
+		}
+	}
+	if PointySouthEast.Opposite() != PointyNorthWest || FlatNorth.Opposite() != FlatSouth {
+		t.Error("named directions are not opposite")
+	}
+}
+
+func sign(v float64) float64 {
+	switch {
+	case v > 1e-9:
+		return 1
+	case v < -1e-9:
+		return -1
+	}
+	return 0
+}
+
+// TestPointyPickingAndWalls verifies picking and side walls work for a
+// pointy-top layout
+func TestPointyPickingAndWalls(t *testing.T) {
+	config := DefaultLayoutConfig()
+	config.Orientation = Pointy
+	layout := NewLayoutWithConfig(config)
+	board := NewHexagonalMap[float64](4)
+	board.Set(Hex{0, 1}, 2)
+	height := func(h Hex) float64 {
+		v, _ := board.Get(h)
+		return v
+	}
+
+	for _, h := range []Hex{{-2, 0}, {3, -3}, {2, -2}} {
+		x, y := layout.HexToPixel(h.Q, h.R)
+		if got, ok := layout.PickHex(x, y, board.Contains, height, 2); !ok || got != h {
+			t.Errorf("PickHex at %v = %v, %v", h, got, ok)
+		}
+	}
+	x, y := layout.HexToPixelAt(0, 1, 2)
+	if got, ok := layout.PickHex(x, y, board.Contains, height, 2); !ok || got != (Hex{0, 1}) {
+		t.Errorf("PickHex on raised top = %v, %v, want (0,1)", got, ok)
+	}
+
+	// A pointy hex shows its two lower sides and, having no bottom edge,
+	// nothing else
+	if walls := layout.SideWalls(Hex{0, 1}, height); len(walls) != 2 {
+		t.Errorf("raised pointy hex has %d walls, want 2", len(walls))
+	}
+}
+
+// TestRotateAndReflect verifies rotations follow the direction ring and
+// reflections fix their axis, around any center
+func TestRotateAndReflect(t *testing.T) {
+	center := Hex{3, -2}
+	for d := Direction(0); d < 6; d++ {
+		h := Neighbor(center, d)
+		for steps := -7; steps <= 7; steps++ {
+			if got, want := h.RotateAround(center, steps), Neighbor(center, d.Rotate(steps)); got != want {
+				t.Errorf("%v rotated %d steps = %v, want %v", h, steps, got, want)
+			}
+		}
+	}
+
+	for _, h := range Range(center, 4) {
+		if got := h.RotateAround(center, 6); got != h {
+			t.Errorf("full turn moved %v to %v", h, got)
+		}
+		for _, axis := range []Axis{AxisQ, AxisR, AxisS} {
+			m := h.ReflectAround(center, axis)
+			if m.ReflectAround(center, axis) != h {
+				t.Errorf("reflecting %v twice across %d did not return it", h, axis)
+			}
+			if HexDistance(center, m) != HexDistance(center, h) {
+				t.Errorf("reflection of %v across %d changed its distance", h, axis)
+			}
+		}
+	}
+
+	// AxisQ keeps q fixed, and each axis swaps the other two coordinates
+	if got := (Hex{2, -1}).ReflectAround(Hex{}, AxisQ); got != (Hex{2, -1}) {
+		t.Errorf("(2,-1) lies on the q mirror line but moved to %v", got)
+	}
+	if got := (Hex{1, 0}).ReflectAround(Hex{}, AxisS); got != (Hex{0, 1}) {
+		t.Errorf("(1,0) reflected across s = %v, want (0,1)", got)
+	}
+}
+
+// TestTransformCompose verifies transforms chain in order and map slices
+func TestTransformCompose(t *testing.T) {
+	pattern := []Hex{{0, 0}, {1, 0}, {2, 0}}
+	stamped := Rotation(Hex{}, 2).Then(Translation(Hex{5, 5})).Apply(pattern)
+	want := []Hex{{5, 5}, {5, 4}, {5, 3}}
+	for i := range want {
+		if stamped[i] != want[i] {
+			t.Fatalf("stamped = %v, want %v", stamped, want)
+		}
+	}
+
+	spread := Scaling(Hex{1, 1}, 3).Apply([]Hex{{1, 1}, {2, 1}})
+	if spread[0] != (Hex{1, 1}) || spread[1] != (Hex{4, 1}) || HexDistance(spread[0], spread[1]) != 3 {
+		t.Errorf("scaled = %v", spread)
+	}
+}
+
+// TestSymmetrize verifies rotational copies are added without duplicates
+func TestSymmetrize(t *testing.T) {
+	if got := Symmetrize([]Hex{{2, 0}}, Hex{}, 6); len(got) != 6 {
+		t.Errorf("six-fold copies = %v", got)
+	}
+	if got := Symmetrize([]Hex{{2, 0}, {-2, 0}}, Hex{}, 2); len(got) != 2 {
+		t.Errorf("two-fold copies of a symmetric pattern = %v", got)
+	}
+	three := toSet(Symmetrize([]Hex{{1, 0}}, Hex{}, 3))
+	if len(three) != 3 || !three[Hex{-1, 1}] || !three[Hex{0, -1}] {
+		t.Errorf("three-fold copies = %v", three)
+	}
+	if got := Symmetrize([]Hex{{1, 0}}, Hex{}, 4); len(got) != 1 {
+		t.Errorf("order 4 should fall back to the original, got %v", got)
+	}
+}
+
+// pathBenchmarkMap builds a rectangular map with scattered walls and keeps
+// the two far corners open.
+func pathBenchmarkMap(size int64, seed uint64) (*Map[bool], Hex, Hex) {
+	board := NewRectangularMap[bool](size, size)
+	rng := rand.New(rand.NewPCG(seed, 7))
+	board.Fill(func(Hex) bool { return rng.IntN(4) != 0 })
+	hexes := board.Hexes()
+	start, goal := hexes[0], hexes[len(hexes)-1]
+	board.Set(start, true)
+	board.Set(goal, true)
+	return board, start, goal
+}
+
+// TestPathfinderMatchesFindWeightedPath verifies the reusable pathfinder finds
+// paths exactly as cheap as the reference search, bounded or not, and with
+// or without straight-line tie-breaking
+func TestPathfinderMatchesFindWeightedPath(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	board, _, _ := pathBenchmarkMap(20, 1)
+	bounded := NewBoundedPathfinder(board.Hexes())
+	unbounded := NewPathfinder()
+	hexes := board.Hexes()
+
+	for trial := 0; trial < 200; trial++ {
+		board.Fill(func(Hex) bool { return rng.IntN(4) != 0 })
+		walkable := board.Walkable(func(open bool) bool { return open })
+		cost := HexCost(func(h Hex) int { return 1 + int(h.Q+h.R)&3 })
+		start, goal := hexes[rng.IntN(len(hexes))], hexes[rng.IntN(len(hexes))]
+		bounded.StraightLines = trial%2 == 0
+
+		wantPath, want := FindWeightedPath(start, goal, walkable, cost, 1)
+		for name, p := range map[string]*Pathfinder{"bounded": bounded, "unbounded": unbounded} {
+			path, got := p.FindWeightedPath(start, goal, walkable, cost, 1)
+			if got != want || (path == nil) != (wantPath == nil) {
+				t.Fatalf("trial %d %s: cost %d, want %d", trial, name, got, want)
+			}
+			if path == nil {
+				continue
+			}
+			if path[0] != start || path[len(path)-1] != goal {
+				t.Fatalf("trial %d %s: path runs %v to %v", trial, name, path[0], path[len(path)-1])
+			}
+			total := 0
+			for i := 1; i < len(path); i++ {
+				if HexDistance(path[i-1], path[i]) != 1 || !walkable(path[i]) {
+					t.Fatalf("trial %d %s: bad step %v -> %v", trial, name, path[i-1], path[i])
+				}
+				total += cost(path[i-1], path[i])
+			}
+			if total != got {
+				t.Fatalf("trial %d %s: path costs %d, reported %d", trial, name, total, got)
+			}
+		}
+	}
+
+	// A bounded pathfinder cannot leave its bounds
+	if path := bounded.FindPath(Hex{}, Hex{-5, 0}, func(Hex) bool { return true }); path != nil {
+		t.Errorf("bounded pathfinder left its bounds: %v", path)
+	}
+}
+
+// TestPathfinderStraightLines verifies tie-breaking keeps open-ground paths
+// next to the straight line instead of running along one axis then the other
+func TestPathfinderStraightLines(t *testing.T) {
+	open := func(Hex) bool { return true }
+	p := NewPathfinder()
+	p.StraightLines = true
+
+	start, goal := Hex{0, 0}, Hex{8, 4}
+	line := toSet(HexLine(start, goal))
+	path := p.FindPath(start, goal, open)
+	if len(path) != int(HexDistance(start, goal))+1 {
+		t.Fatalf("path has %d hexes, want %d", len(path), HexDistance(start, goal)+1)
+	}
+	for _, h := range path {
+		near := line[h]
+		for _, n := range GetNeighbors(h) {
+			near = near || line[n]
+		}
+		if !near {
+			t.Errorf("path strays to %v, away from the straight line", h)
+		}
+	}
+}
+
+// TestPathfinderDoesNotAllocate verifies repeated searches reuse their buffers
+func TestPathfinderDoesNotAllocate(t *testing.T) {
+	board, start, goal := pathBenchmarkMap(32, 2)
+	walkable := board.Walkable(func(open bool) bool { return open })
+
+	for name, p := range map[string]*Pathfinder{
+		"bounded":   NewBoundedPathfinder(board.Hexes()),
+		"unbounded": NewPathfinder(),
+	} {
+		p.StraightLines = true
+		p.FindPath(start, goal, walkable)
+		allocs := testing.AllocsPerRun(20, func() {
+			p.FindPath(start, goal, walkable)
+		})
+		if allocs != 0 {
+			t.Errorf("%s pathfinder allocated %.0f times per search", name, allocs)
+		}
+	}
+}
+
+func BenchmarkFindPath(b *testing.B) {
+	board, start, goal := pathBenchmarkMap(64, 3)
+	walkable := board.Walkable(func(open bool) bool { return open })
+	b.ReportAllocs()
+	for b.Loop() {
+		FindPath(start, goal, walkable)
+	}
+}
+
+func BenchmarkPathfinderBounded(b *testing.B) {
+	board, start, goal := pathBenchmarkMap(64, 3)
+	walkable := board.Walkable(func(open bool) bool { return open })
+	p := NewBoundedPathfinder(board.Hexes())
+	b.ReportAllocs()
+	for b.Loop() {
+		p.FindPath(start, goal, walkable)
+	}
+}
+
+func BenchmarkPathfinderUnbounded(b *testing.B) {
+	board, start, goal := pathBenchmarkMap(64, 3)
+	walkable := board.Walkable(func(open bool) bool { return open })
+	p := NewPathfinder()
+	b.ReportAllocs()
+	for b.Loop() {
+		p.FindPath(start, goal, walkable)
+	}
+}
+
+// TestFindPathGivesUp verifies the plain searches return for a walled-in
+// goal on an endless plane
+func TestFindPathGivesUp(t *testing.T) {
+	goal := Hex{12, -3}
+	walls := toSet(Ring(goal, 2))
+	if path := FindPath(Hex{}, goal, func(h Hex) bool { return !walls[h] }); path != nil {
+		t.Errorf("path to walled-in goal: %v", path)
+	}
+
+	isEdgeBlocked := func(e Edge) bool {
+		a, b := e.Hexes()
+		return a == goal || b == goal
+	}
+	everywhere := func(Hex) bool { return true }
+	if path, cost := FindPathWithEdges(Hex{}, goal, everywhere, isEdgeBlocked, uniformCost, 1); path != nil || cost != 0 {
+		t.Errorf("path through walls: %v, cost %d", path, cost)
+	}
+}
+
+// TestSearchLimits verifies a search toward an unreachable goal on an
+// endless plane stops at its budget and can still head toward the goal
+func TestSearchLimits(t *testing.T) {
+	goal := Hex{12, -3}
+	walls := toSet(Ring(goal, 2))
+	walkable := func(h Hex) bool { return !walls[h] }
+	ctx := context.Background()
+
+	result := Search(ctx, Hex{}, goal, walkable, uniformCost, 1, SearchLimits{MaxNodes: 500})
+	if result.Status != PathBudgetExceeded || result.Path != nil || result.Expanded != 500 {
+		t.Errorf("node budget: status %v, path %v, expanded %d", result.Status, result.Path, result.Expanded)
+	}
+
+	result = Search(ctx, Hex{}, goal, walkable, uniformCost, 1, SearchLimits{MaxNodes: 500, Closest: true})
+	if result.Found() || len(result.Path) == 0 {
+		t.Fatalf("closest: status %v, path %v", result.Status, result.Path)
+	}
+	if end := result.Path[len(result.Path)-1]; HexDistance(end, goal) != 3 || result.Cost != len(result.Path)-1 {
+		t.Errorf("closest path ends at %v, distance %d, cost %d", end, HexDistance(end, goal), result.Cost)
+	}
+
+	result = Search(ctx, Hex{}, Hex{5, 0}, walkable, uniformCost, 1, SearchLimits{MaxCost: 4, Closest: true})
+	if result.Status != PathBudgetExceeded || result.Cost != 4 || result.Path[len(result.Path)-1] != (Hex{4, 0}) {
+		t.Errorf("cost budget: status %v, cost %d, path %v", result.Status, result.Cost, result.Path)
+	}
+	// A walled-in goal is unreachable however much budget is left over
+	if result := Search(ctx, Hex{}, goal, walkable, uniformCost, 1, SearchLimits{MaxCost: 20}); result.Status != PathUnreachable {
+		t.Errorf("walled-in goal within cost budget: status %v", result.Status)
+	}
+	if result := Search(ctx, Hex{}, Hex{3, 0}, walkable, uniformCost, 1, SearchLimits{MaxCost: 4}); !result.Found() || result.Cost != 3 {
+		t.Errorf("goal within cost budget: status %v, cost %d", result.Status, result.Cost)
+	}
+}
+
+// TestSearchFailureReasons verifies each way a search can fail is reported
+func TestSearchFailureReasons(t *testing.T) {
+	ctx := context.Background()
+	board := NewHexagonalMap[bool](4)
+	board.Fill(func(Hex) bool { return true })
+	for _, h := range Ring(Hex{3, 0}, 1) {
+		board.Set(h, false)
+	}
+	board.Set(Hex{-4, 0}, false)
+	walkable := board.Walkable(func(open bool) bool { return open })
+
+	tests := []struct {
+		name        string
+		start, goal Hex
+		want        PathStatus
+	}{
+		{"found", Hex{}, Hex{-3, 0}, PathFound},
+		{"unreachable", Hex{}, Hex{3, 0}, PathUnreachable},
+		{"start blocked", Hex{-4, 0}, Hex{}, PathStartBlocked},
+		{"goal blocked", Hex{}, Hex{-4, 0}, PathGoalBlocked},
+		{"goal off the map", Hex{}, Hex{9, 0}, PathGoalBlocked},
+	}
+	for _, tt := range tests {
+		for name, p := range map[string]*Pathfinder{"bounded": NewBoundedPathfinder(board.Hexes()), "unbounded": NewPathfinder()} {
+			if got := p.Search(ctx, tt.start, tt.goal, walkable, uniformCost, 1, SearchLimits{}); got.Status != tt.want {
+				t.Errorf("%s %s: status %v, want %v", tt.name, name, got.Status, tt.want)
+			}
+		}
+	}
+
+	// A blocked goal can still be approached
+	result := Search(ctx, Hex{}, Hex{-4, 0}, walkable, uniformCost, 1, SearchLimits{Closest: true})
+	if result.Status != PathGoalBlocked || result.Path[len(result.Path)-1] != (Hex{-3, 0}) {
+		t.Errorf("closest to blocked goal: status %v, path %v", result.Status, result.Path)
+	}
+}
+
+// TestSearchCancellation verifies a search with no limits on an endless
+// plane ends once its context does
+func TestSearchCancellation(t *testing.T) {
+	goal := Hex{5, 5}
+	walls := toSet(Ring(goal, 1))
+	walkable := func(h Hex) bool { return !walls[h] }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := Search(ctx, Hex{}, goal, walkable, uniformCost, 1, SearchLimits{}); result.Status != PathCancelled {
+		t.Errorf("cancelled context: status %v", result.Status)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if result := Search(ctx, Hex{}, goal, walkable, uniformCost, 1, SearchLimits{}); result.Status != PathCancelled {
+		t.Errorf("timed out search: status %v", result.Status)
+	}
+}
+
+// TestFlowFieldMatchesFindWeightedPath verifies every unit's flow field
+// distance and route match a path search to its nearest goal
+func TestFlowFieldMatchesFindWeightedPath(t *testing.T) {
+	board, _, _ := pathBenchmarkMap(16, 5)
+	walkable := board.Walkable(func(open bool) bool { return open })
+	cost := HexCost(func(h Hex) int { return 1 + int(h.Q*h.R)&1 })
+	goals := []Hex{{3, 2}, {12, -3}}
+	for _, g := range goals {
+		board.Set(g, true)
+	}
+
+	field := NewFlowField(goals, 0, walkable, cost)
+	for _, h := range board.Hexes() {
+		if !walkable(h) {
+			continue
+		}
+		want := -1
+		for _, g := range goals {
+			if path, c := FindWeightedPath(h, g, walkable, cost, 1); path != nil && (want < 0 || c < want) {
+				want = c
+			}
+		}
+
+		got, ok := field.Value(h)
+		if ok != (want >= 0) || ok && got != want {
+			t.Fatalf("%v: flow value %d, %v, want %d", h, got, ok, want)
+		}
+		if !ok {
+			continue
+		}
+
+		path := field.PathFrom(h)
+		total := 0
+		for i := 1; i < len(path); i++ {
+			total += cost(path[i-1], path[i])
+		}
+		if end := path[len(path)-1]; end != goals[0] && end != goals[1] || total != want {
+			t.Fatalf("%v: path ends at %v costing %d, want a goal at %d", h, end, total, want)
+		}
+	}
+
+	if _, ok := field.Next(goals[0]); ok {
+		t.Error("a goal has a next step")
+	}
+	if limited := NewFlowField(goals[:1], 3, walkable, cost); func() bool {
+		_, ok := limited.Value(Hex{12, -3})
+		return ok
+	}() {
+		t.Error("maxCost did not stop the flood")
+	}
+}
+
+// TestFlowFieldFlee verifies fleeing units move away from the goal and
+// escape a dead end past the pursuer rather than cowering in it
+func TestFlowFieldFlee(t *testing.T) {
+	// A corridor running east from a hunter, with a short dead-end spur
+	// next to the hunter and a long open run beyond it. A two-hex bypass
+	// skirts the hunter's north side.
+	open := map[Hex]bool{{0, -1}: true, {1, -1}: true}
+	for q := int64(-3); q <= 24; q++ {
+		open[Hex{q, 0}] = true
+	}
+	hunter := Hex{0, 0}
+	walkable := func(h Hex) bool { return open[h] }
+
+	flee := NewFlowField([]Hex{hunter}, 0, walkable, uniformCost).Flee(DefaultFleeScale)
+
+	// Anyone east of the hunter runs to the far east end
+	if path := flee.PathFrom(Hex{2, 0}); path[len(path)-1] != (Hex{24, 0}) {
+		t.Errorf("east of the hunter fled along %v", path)
+	}
+	// Stepping away from the hunter always looks better than standing still
+	for q := int64(1); q < 24; q++ {
+		if next, ok := flee.Next(Hex{q, 0}); !ok || next.Q != q+1 {
+			t.Errorf("(%d,0) fled to %v, %v", q, next, ok)
+		}
+	}
+	// The dead end is only three hexes deep, so a unit in it breaks past
+	// the hunter toward the long run, without stepping on the hunter
+	if next, ok := flee.Next(Hex{-1, 0}); !ok || next != (Hex{0, -1}) {
+		t.Errorf("(-1,0) fled to %v, %v, want the bypass past the hunter", next, ok)
+	}
+	if _, ok := flee.Value(hunter); ok {
+		t.Error("the flee field covers the hunter's hex")
+	}
+	// With the bypass walled off there is no way past, and the unit backs
+	// into the dead end instead
+	delete(open, Hex{0, -1})
+	cornered := NewFlowField([]Hex{hunter}, 0, walkable, uniformCost).Flee(DefaultFleeScale)
+	if next, ok := cornered.Next(Hex{-1, 0}); !ok || next != (Hex{-2, 0}) {
+		t.Errorf("cornered (-1,0) fled to %v, %v, want deeper into the dead end", next, ok)
+	}
+}
+
+// TestHierarchicalPathfinder verifies hierarchical paths are valid, exist
+// exactly when a full search finds one, and stay close to the cheapest
+func TestHierarchicalPathfinder(t *testing.T) {
+	board, _, _ := pathBenchmarkMap(40, 11)
+	walkable := board.Walkable(func(open bool) bool { return open })
+	cost := HexCost(func(h Hex) int { return 1 + int(h.Q*h.R)&1 })
+	hp := NewHierarchicalPathfinder(board.Hexes(), 8, walkable, nil, cost, 1)
+
+	rng := rand.New(rand.NewPCG(3, 9))
+	hexes := board.Hexes()
+	extra := 0
+	for range 200 {
+		start, goal := hexes[rng.IntN(len(hexes))], hexes[rng.IntN(len(hexes))]
+		want, wantCost := FindWeightedPath(start, goal, walkable, cost, 1)
+		got, gotCost := hp.FindPath(start, goal)
+		if (got == nil) != (want == nil) {
+			t.Fatalf("%v to %v: hierarchical path %v, full search %v", start, goal, got, want)
+		}
+		if got == nil {
+			continue
+		}
+
+		if got[0] != start || got[len(got)-1] != goal {
+			t.Fatalf("%v to %v: path runs from %v to %v", start, goal, got[0], got[len(got)-1])
+		}
+		total := 0
+		for i := 1; i < len(got); i++ {
+			if HexDistance(got[i-1], got[i]) != 1 || !walkable(got[i]) {
+				t.Fatalf("%v to %v: bad step from %v to %v", start, goal, got[i-1], got[i])
+			}
+			total += cost(got[i-1], got[i])
+		}
+		if total != gotCost || gotCost < wantCost {
+			t.Fatalf("%v to %v: cost %d, steps add up to %d, cheapest %d", start, goal, gotCost, total, wantCost)
+		}
+		extra += gotCost - wantCost
+	}
+	if extra > 200*2 {
+		t.Errorf("hierarchical paths cost %d more than the cheapest in total", extra)
+	}
+}
+
+// TestHierarchicalPathfinderInvalidation verifies opening a door or breaking
+// a wall only changes paths once the change is reported
+func TestHierarchicalPathfinderInvalidation(t *testing.T) {
+	// Two halves of a parallelogram split by a wall of hexes along q = 10,
+	// with a single gap closed by a door on its west edge
+	board := NewParallelogramMap[bool](0, 19, 0, 9)
+	board.Fill(func(h Hex) bool { return h.Q != 10 || h.R == 4 })
+	// Block the gap's other western neighbor so the door is the only way in
+	board.Set(Hex{9, 5}, false)
+	walkable := board.Walkable(func(open bool) bool { return open })
+	door, _ := EdgeBetween(Hex{9, 4}, Hex{10, 4})
+	doorOpen := false
+	isEdgeBlocked := func(e Edge) bool { return e == door && !doorOpen }
+
+	hp := NewHierarchicalPathfinder(board.Hexes(), 4, walkable, isEdgeBlocked, uniformCost, 1)
+	start, goal := Hex{2, 2}, Hex{17, 7}
+	if path, _ := hp.FindPath(start, goal); path != nil {
+		t.Fatalf("path through a closed door: %v", path)
+	}
+
+	doorOpen = true
+	if path, _ := hp.FindPath(start, goal); path != nil {
+		t.Fatalf("door opened without invalidating, yet found %v", path)
+	}
+	hp.InvalidateEdge(door)
+	path, cost := hp.FindPath(start, goal)
+	if !slices.Contains(path, Hex{10, 4}) {
+		t.Fatalf("after opening the door: %v", path)
+	}
+	if _, want := FindPathWithEdges(start, goal, walkable, isEdgeBlocked, uniformCost, 1); cost < want {
+		t.Errorf("cost %d below the cheapest %d", cost, want)
+	}
+
+	doorOpen = false
+	hp.InvalidateEdge(door)
+	if path, _ := hp.FindPath(start, goal); path != nil {
+		t.Fatalf("path after closing the door: %v", path)
+	}
+
+	board.Set(Hex{10, 1}, true)
+	hp.InvalidateHex(Hex{10, 1})
+	if path, _ := hp.FindPath(start, goal); !slices.Contains(path, Hex{10, 1}) {
+		t.Errorf("after breaking the wall: %v", path)
+	}
+}
+
+// TestZoneOfControlStops verifies a unit cannot move through hexes next to
+// an enemy, but can end its move on one or walk out of one it starts in
+func TestZoneOfControlStops(t *testing.T) {
+	board := NewHexagonalMap[bool](6)
+	enemy := Hex{3, 0}
+	zone := ZoneOfControl{
+		Occupant: func(h Hex) Occupant {
+			if h == enemy {
+				return Enemy
+			}
+			return Unoccupied
+		},
+		Stop: true,
+	}
+
+	result := FindZonePath(Hex{0, 0}, Hex{6, 0}, board.Contains, uniformCost, 1, zone)
+	if result.Path == nil {
+		t.Fatal("no path around the enemy")
+	}
+	for _, h := range result.Path[1 : len(result.Path)-1] {
+		if zone.Controlled(h) {
+			t.Errorf("path %v passes through controlled %v", result.Path, h)
+		}
+	}
+	if result.Cost <= 6 || len(result.Attacks) != 0 {
+		t.Errorf("cost %d, attacks %v", result.Cost, result.Attacks)
+	}
+
+	// Ending next to the enemy is allowed
+	if result := FindZonePath(Hex{0, 0}, Hex{2, 0}, board.Contains, uniformCost, 1, zone); result.Cost != 2 {
+		t.Errorf("into the zone: %v, cost %d", result.Path, result.Cost)
+	}
+
+	// Starting next to the enemy, walking away provokes it
+	result = FindZonePath(Hex{2, 0}, Hex{-2, 0}, board.Contains, uniformCost, 1, zone)
+	want := []OpportunityAttack{{Hex: Hex{2, 0}, Attacker: enemy}}
+	if !slices.Equal(result.Attacks, want) || result.Cost != 4 {
+		t.Errorf("leaving the zone: path %v, attacks %v", result.Path, result.Attacks)
+	}
+
+	reach := ReachableInZone(Hex{0, 0}, 5, board.Contains, uniformCost, zone)
+	if _, ok := reach.CostTo(Hex{2, 0}); !ok {
+		t.Error("controlled hex out of reach")
+	}
+	if _, ok := reach.CostTo(Hex{4, -1}); !ok {
+		t.Error("controlled hex behind the enemy out of reach")
+	}
+	if _, ok := reach.CostTo(Hex{5, -1}); ok {
+		t.Error("moved through the zone of control")
+	}
+}
+
+// TestZoneOfControlPenalty verifies controlled hexes cost extra and that
+// sliding along an enemy provokes nothing until the unit leaves its reach
+func TestZoneOfControlPenalty(t *testing.T) {
+	board := NewHexagonalMap[bool](6)
+	enemy := Hex{0, 0}
+	zone := ZoneOfControl{
+		Occupant: func(h Hex) Occupant {
+			if h == enemy {
+				return Enemy
+			}
+			return Unoccupied
+		},
+		Penalty: 10,
+	}
+
+	// Going around the far side of the ring beats paying the penalty
+	result := FindZonePath(Hex{-2, 0}, Hex{2, 0}, board.Contains, uniformCost, 1, zone)
+	if result.Cost != 6 || len(result.Attacks) != 0 {
+		t.Errorf("around the enemy: %v, cost %d, attacks %v", result.Path, result.Cost, result.Attacks)
+	}
+
+	// A path that hugs the enemy only provokes when it steps away
+	path := []Hex{{-1, 0}, {-1, 1}, {0, 1}, {1, 0}, {2, 0}}
+	want := []OpportunityAttack{{Hex: Hex{1, 0}, Attacker: enemy}}
+	if got := zone.OpportunityAttacks(path); !slices.Equal(got, want) {
+		t.Errorf("attacks %v, want %v", got, want)
+	}
+
+	reach := ReachableInZone(Hex{-2, 0}, 11, board.Contains, uniformCost, zone)
+	if c, _ := reach.CostTo(Hex{-1, 0}); c != 11 {
+		t.Errorf("entering the zone cost %d, want 11", c)
+	}
+}
+
+// TestConnectedRegionsAndChokepoints verifies regions and chokepoints
+// against blocking each hex in turn and counting the regions left
+func TestConnectedRegionsAndChokepoints(t *testing.T) {
+	board, _, _ := pathBenchmarkMap(12, 3)
+	hexes := board.Hexes()
+	walkable := board.Walkable(func(open bool) bool { return open })
+
+	regions := ConnectedRegions(hexes, walkable)
+	total := 0
+	for _, region := range regions {
+		total += len(region)
+		for _, h := range region[1:] {
+			if FindPath(region[0], h, walkable) == nil {
+				t.Fatalf("%v and %v share a region but are not connected", region[0], h)
+			}
+		}
+	}
+	if open := len(slices.DeleteFunc(slices.Clone(hexes), func(h Hex) bool { return !walkable(h) })); total != open || len(regions) < 2 {
+		t.Errorf("%d regions covering %d hexes, want all %d open hexes", len(regions), total, open)
+	}
+
+	chokepoints := toSet(Chokepoints(hexes, walkable))
+	for _, h := range hexes {
+		if !walkable(h) {
+			continue
+		}
+		without := ConnectedRegions(hexes, func(x Hex) bool { return x != h && walkable(x) })
+		splits := len(without) > len(regions)
+		if splits != chokepoints[h] {
+			t.Errorf("%v: splits the map %v, chokepoint %v", h, splits, chokepoints[h])
+		}
+	}
+	if len(chokepoints) == 0 {
+		t.Error("no chokepoints found")
+	}
+}
+
+// TestWallDistanceAndOpenAreas verifies the wall distance field against a
+// ring-by-ring search and that open areas keep their clearance
+func TestWallDistanceAndOpenAreas(t *testing.T) {
+	board := NewHexagonalMap[bool](8)
+	board.Fill(func(h Hex) bool { return HexDistance(h, Hex{-3, 1}) > 1 })
+	hexes := board.Hexes()
+	walkable := board.Walkable(func(open bool) bool { return open })
+
+	distances := WallDistance(hexes, walkable)
+	for h, got := range distances.All() {
+		want := 0
+		if walkable(h) {
+			for want = 1; ; want++ {
+				if slices.ContainsFunc(Ring(h, int64(want)), func(x Hex) bool { return !walkable(x) }) {
+					break
+				}
+			}
+		}
+		if got != want {
+			t.Errorf("%v: wall distance %d, want %d", h, got, want)
+		}
+	}
+
+	for _, clearance := range []int{0, -2} {
+		if got, want := OpenAreas(hexes, walkable, clearance), ConnectedRegions(hexes, walkable); !slices.EqualFunc(got, want, slices.Equal) {
+			t.Errorf("clearance %d: open areas %v, want every walkable hex", clearance, got)
+		}
+	}
+
+	areas := OpenAreas(hexes, walkable, 4)
+	if len(areas) == 0 {
+		t.Fatal("no open areas")
+	}
+	for _, area := range areas {
+		for _, h := range area {
+			for _, x := range Range(h, 3) {
+				if !walkable(x) {
+					t.Fatalf("%v in an open area is within 3 of the wall at %v", h, x)
+				}
+			}
+		}
+	}
+}
+
+// TestInfluenceMap verifies threat falls off with distance, stops at walls
+// and combines by sum and max
+func TestInfluenceMap(t *testing.T) {
+	wall := Hex{2, 0}
+	board := NewHexagonalMap[bool](6)
+	influence := NewInfluenceMap(board.Hexes(), func(h Hex) bool { return h == wall })
+	const player, monsters Faction = 0, 1
+	influence.Add(monsters, Hex{0, 0}, 4, 3)
+	influence.Add(monsters, Hex{1, 0}, 2, 1)
+	influence.Add(player, Hex{-3, 0}, 8, 3)
+
+	tests := []struct {
+		h        Hex
+		sum, max float64
+	}{
+		{Hex{0, 0}, 4 + 1, 4},
+		{Hex{1, 0}, 3 + 2, 3},
+		{Hex{-2, 0}, 2, 2},
+		{Hex{3, 0}, 0, 0},
+		{Hex{0, 4}, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := influence.Sum(tt.h, monsters); got != tt.sum {
+			t.Errorf("Sum(%v) = %v, want %v", tt.h, got, tt.sum)
+		}
+		if got := influence.Max(tt.h, monsters); got != tt.max {
+			t.Errorf("Max(%v) = %v, want %v", tt.h, got, tt.max)
+		}
+	}
+
+	if got := influence.Sum(Hex{-2, 0}); got != 2+6 {
+		t.Errorf("Sum over every faction = %v, want 8", got)
+	}
+	if got := influence.Threat(Hex{-2, 0}, player); got != 2 {
+		t.Errorf("Threat to player = %v, want 2", got)
+	}
+
+	// Layers add up in faction order, so totals never differ in the last bit
+	mixed := NewInfluenceMap(board.Hexes(), func(Hex) bool { return false })
+	for i, faction := range []Faction{7, 2, 9, 4, 5} {
+		mixed.Add(faction, Hex{}, 0.1*float64(i+1), 2)
+	}
+	at := Hex{1, 0}
+	want := 0.0
+	for _, faction := range []Faction{2, 4, 5, 7, 9} {
+		want += mixed.Sum(at, faction)
+	}
+	for range 50 {
+		if got := mixed.Sum(at); got != want {
+			t.Fatalf("Sum = %v, want %v", got, want)
+		}
+	}
+
+	influence.Clear()
+	if got := influence.Sum(Hex{0, 0}); got != 0 {
+		t.Errorf("Sum after Clear = %v", got)
+	}
+}
+
+// TestSafest verifies a unit moves out of reach of the threat, taking the
+// cheapest way there and staying put when nowhere is safer
+func TestSafest(t *testing.T) {
+	board := NewHexagonalMap[bool](6)
+	influence := NewInfluenceMap(board.Hexes(), func(Hex) bool { return false })
+	influence.Add(1, Hex{0, 0}, 1, 2)
+	danger := func(h Hex) float64 { return influence.Threat(h, 0) }
+
+	reach := Reachable(Hex{1, 0}, 2, board.Contains, uniformCost, nil)
+	safest := reach.Safest(danger)
+	if danger(safest) != 0 {
+		t.Errorf("Safest = %v with danger %v", safest, danger(safest))
+	}
+	if cost, _ := reach.CostTo(safest); cost != 2 {
+		t.Errorf("Safest = %v costs %d, want 2", safest, cost)
+	}
+
+	reach = Reachable(Hex{5, 0}, 2, board.Contains, uniformCost, nil)
+	if got := reach.Safest(danger); got != (Hex{5, 0}) {
+		t.Errorf("already safe unit moved to %v", got)
+	}
+}
+
+func testMapFile() *MapFile {
+	terrains := []string{"grassland", "forest", "hills", "shallow water"}
+	board := NewOffsetRectangularMap[int](7, 5, OddR)
+	board.Fill(func(h Hex) int { return int(h.Q*3+h.R*5) & 15 })
+	f := NewMapFile(board, func(v int) (string, float64) {
+		return terrains[v%len(terrains)], float64(v) / 8
+	})
+	f.Shape.Kind, f.Shape.System, f.Shape.Width, f.Shape.Height = ShapeRectangle, OddR, 7, 5
+	f.Edges = []EdgeFeature{
+		{Edge: EdgeOf(Hex{1, 1}, 0), Feature: "wall"},
+		{Edge: EdgeOf(Hex{1, 1}, 4), Feature: "door"},
+		{Edge: EdgeOf(Hex{3, 2}, 5), Feature: "river"},
+	}
+	f.Spawns = []Spawn{{Name: "party", Hex: Hex{0, 0}}, {Name: "boss", Hex: Hex{3, 4}}}
+	f.Metadata = map[string]string{"name": "Test Ford", "author": "tests"}
+	return f
+}
+
+// TestMapFileRoundTrip verifies a map survives being written and read back
+// in both formats, and that the binary one is the smaller
+func TestMapFileRoundTrip(t *testing.T) {
+	f := testMapFile()
+
+	var jsonBuf, binaryBuf bytes.Buffer
+	if err := f.WriteJSON(&jsonBuf); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.WriteBinary(&binaryBuf); err != nil {
+		t.Fatal(err)
+	}
+	if binaryBuf.Len()*4 > jsonBuf.Len() {
+		t.Errorf("binary is %d bytes, JSON %d", binaryBuf.Len(), jsonBuf.Len())
+	}
+
+	fromJSON, err := ReadMapFileJSON(bytes.NewReader(jsonBuf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBinary, err := ReadMapFileBinary(bytes.NewReader(binaryBuf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, got := range map[string]*MapFile{"JSON": fromJSON, "binary": fromBinary} {
+		if !reflect.DeepEqual(got, f) {
+			t.Errorf("%s round trip:\n got %+v\nwant %+v", name, got, f)
+		}
+	}
+
+	board := MapFromFile(fromBinary, func(tile Tile) Tile { return tile })
+	if board.Len() != 35 || board.Orientation() != Pointy {
+		t.Fatalf("loaded map has %d hexes, orientation %d", board.Len(), board.Orientation())
+	}
+	for i, h := range board.Hexes() {
+		if tile, _ := board.Get(h); tile != f.Tiles[i] {
+			t.Errorf("%v: loaded %+v, want %+v", h, tile, f.Tiles[i])
+		}
+	}
+}
+
+// TestMapFileMigration verifies older files are upgraded through the
+// migration hook and newer or broken ones are rejected
+func TestMapFileMigration(t *testing.T) {
+	old := `{"version": 0, "shape": {"kind": "custom"}, "tiles": [{"hex": {"q": 0, "r": 0}, "terrain": "grass"}]}`
+	if _, err := ReadMapFileJSON(strings.NewReader(old)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("version 0 without a migration: %v", err)
+	}
+
+	Migrations[0] = func(f *MapFile) error {
+		for i := range f.Tiles {
+			if f.Tiles[i].Terrain == "grass" {
+				f.Tiles[i].Terrain = "grassland"
+			}
+		}
+		return nil
+	}
+	t.Cleanup(func() { delete(Migrations, 0) })
+	f, err := ReadMapFileJSON(strings.NewReader(old))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != MapFileVersion || f.Tiles[0].Terrain != "grassland" {
+		t.Errorf("migrated to version %d with terrain %q", f.Version, f.Tiles[0].Terrain)
+	}
+
+	// A binary file from before a layout change is read with its own
+	// layout, then migrated: here a version 0 that wrote each tile's
+	// terrain inline and had no elevation.
+	binaryLayouts[0] = func(d *mapFileDecoder) *MapFile {
+		f := &MapFile{Tiles: make([]Tile, d.count())}
+		for i := range f.Tiles {
+			f.Tiles[i] = Tile{Hex: d.step(Hex{}), Terrain: d.string()}
+		}
+		return f
+	}
+	t.Cleanup(func() { delete(binaryLayouts, 0) })
+	oldBinary := append([]byte("HEXM"), 0, 2, 0, 0, 5, 'g', 'r', 'a', 's', 's', 2, 0, 4, 'b', 'o', 'g', 's')
+	f, err = ReadMapFileBinary(bytes.NewReader(oldBinary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Tile{{Hex: Hex{0, 0}, Terrain: "grassland"}, {Hex: Hex{1, 0}, Terrain: "bogs"}}
+	if f.Version != MapFileVersion || !slices.Equal(f.Tiles, want) {
+		t.Errorf("binary migrated to version %d with tiles %v, want %v", f.Version, f.Tiles, want)
+	}
+
+	newer := fmt.Sprintf(`{"version": %d, "tiles": []}`, MapFileVersion+1)
+	if _, err := ReadMapFileJSON(strings.NewReader(newer)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("newer version: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := testMapFile().WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for _, cut := range []int{0, 3, len(data) / 2, len(data) - 1} {
+		if _, err := ReadMapFileBinary(bytes.NewReader(data[:cut])); !errors.Is(err, ErrNotMapFile) {
+			t.Errorf("binary cut to %d bytes: %v", cut, err)
+		}
+	}
+}
+
+// TestMapFileValidation verifies files that decode but describe a broken
+// map are rejected, and that the JSON uses lower case keys throughout
+func TestMapFileValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(f *MapFile)
+	}{
+		{"orientation", func(f *MapFile) { f.Shape.Orientation = 7 }},
+		{"offset system", func(f *MapFile) { f.Shape.System = -1 }},
+		{"repeated hex", func(f *MapFile) { f.Tiles = append(f.Tiles, f.Tiles[0]) }},
+		{"edge side", func(f *MapFile) { f.Edges[0].Edge.Side = 4 }},
+		{"edge off the map", func(f *MapFile) { f.Edges[0].Edge = EdgeOf(Hex{40, 40}, 0) }},
+		{"spawn off the map", func(f *MapFile) { f.Spawns[1].Hex = Hex{-9, 0} }},
+	}
+	for _, tt := range tests {
+		f := testMapFile()
+		tt.corrupt(f)
+		var jsonBuf, binaryBuf bytes.Buffer
+		if err := f.WriteJSON(&jsonBuf); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.WriteBinary(&binaryBuf); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ReadMapFileJSON(&jsonBuf); got != nil || !errors.Is(err, ErrInvalidMapFile) {
+			t.Errorf("%s JSON: %v, %v", tt.name, got, err)
+		}
+		if got, err := ReadMapFileBinary(&binaryBuf); got != nil || !errors.Is(err, ErrInvalidMapFile) {
+			t.Errorf("%s binary: %v, %v", tt.name, got, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := testMapFile().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if upper := regexp.MustCompile(`"[A-Z]\w*":`).FindString(buf.String()); upper != "" {
+		t.Errorf("JSON has key %s", upper)
+	}
+}
@@ -0,0 +1,370 @@
+package hex
+
+import (
+	"container/heap"
+	"context"
+	"maps"
+	"slices"
+)
+
+// HierarchicalPathfinder plans paths across maps too large to search hex by
+// hex every time. The map is cut into chunks of chunkSize by chunkSize hexes
+// (parallelograms in axial coordinates, which themselves tile like hexes),
+// and every stretch of open border between two chunks gets one entrance.
+// A query searches the small graph of entrances and then fills in each leg
+// with a local A* search that never leaves its chunk.
+//
+// Paths are always valid and are found whenever one exists, but they may
+// cost a little more than FindWeightedPath's, because they are routed
+// through the middle of each entrance.
+//
+// The callbacks are read again whenever part of the graph is rebuilt, so
+// when a door opens or a wall is destroyed, change the state they read and
+// call InvalidateHex or InvalidateEdge. Only the chunks around the change
+// are rebuilt, on the next FindPath. A HierarchicalPathfinder is not safe
+// for concurrent use.
+type HierarchicalPathfinder struct {
+	chunkSize     int64
+	isWalkable    func(Hex) bool
+	isEdgeBlocked func(Edge) bool
+	cost          CostFunc
+	minCost       int
+
+	clusters map[Hex]*cluster
+	dirty    map[Hex]bool
+	finder   *Pathfinder
+}
+
+// cluster is one chunk of the map and its part of the entrance graph.
+type cluster struct {
+	hexes []Hex
+	// entrances holds the chosen border crossings to each neighboring
+	// chunk, keyed by that chunk.
+	entrances map[Hex][]crossing
+	// nodes are the entrance hexes inside the chunk, in draw order.
+	nodes []Hex
+	links map[Hex][]entranceLink
+}
+
+// crossing is a step over a chunk border, from a hex inside the chunk to
+// one outside it.
+type crossing struct {
+	inside, outside Hex
+}
+
+type entranceLink struct {
+	to   Hex
+	cost int
+}
+
+// NewHierarchicalPathfinder builds the entrance graph over hexes, which
+// should be every hex of the map, such as Map.Hexes. isEdgeBlocked may be
+// nil on maps without walls on hex borders. cost and minCost mean the same
+// as for FindWeightedPath.
+func NewHierarchicalPathfinder(hexes []Hex, chunkSize int64, isWalkable func(Hex) bool, isEdgeBlocked func(Edge) bool, cost CostFunc, minCost int) *HierarchicalPathfinder {
+	hp := &HierarchicalPathfinder{
+		chunkSize:     max(chunkSize, 1),
+		isWalkable:    isWalkable,
+		isEdgeBlocked: isEdgeBlocked,
+		cost:          cost,
+		minCost:       max(minCost, 0),
+		clusters:      map[Hex]*cluster{},
+		dirty:         map[Hex]bool{},
+		finder:        NewPathfinder(),
+	}
+	for _, h := range hexes {
+		chunk := hp.chunkOf(h)
+		c, ok := hp.clusters[chunk]
+		if !ok {
+			c = &cluster{entrances: map[Hex][]crossing{}}
+			hp.clusters[chunk] = c
+		}
+		c.hexes = append(c.hexes, h)
+		hp.dirty[chunk] = true
+	}
+	for _, c := range hp.clusters {
+		slices.SortFunc(c.hexes, compareDrawOrder)
+		c.hexes = slices.Compact(c.hexes)
+	}
+	hp.refresh()
+	return hp
+}
+
+// chunkOf returns the coordinates of the chunk h falls in. Neighboring hexes
+// always fall in the same chunk or in neighboring chunks.
+func (hp *HierarchicalPathfinder) chunkOf(h Hex) Hex {
+	return Hex{Q: floorDiv(h.Q, hp.chunkSize), R: floorDiv(h.R, hp.chunkSize)}
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// InvalidateHex marks the chunk around h for rebuilding after h became
+// walkable or blocked.
+func (hp *HierarchicalPathfinder) InvalidateHex(h Hex) {
+	hp.invalidate(hp.chunkOf(h))
+}
+
+// InvalidateEdge marks the chunks on both sides of e for rebuilding after a
+// door on it opened or closed, or a wall along it was built or destroyed.
+func (hp *HierarchicalPathfinder) InvalidateEdge(e Edge) {
+	a, b := e.Hexes()
+	hp.invalidate(hp.chunkOf(a))
+	hp.invalidate(hp.chunkOf(b))
+}
+
+func (hp *HierarchicalPathfinder) invalidate(chunk Hex) {
+	if _, ok := hp.clusters[chunk]; ok {
+		hp.dirty[chunk] = true
+	}
+}
+
+// refresh rebuilds the entrances on every border of a dirty chunk, then the
+// links of every chunk that shares one of those borders, since its set of
+// entrances may have changed too.
+func (hp *HierarchicalPathfinder) refresh() {
+	if len(hp.dirty) == 0 {
+		return
+	}
+	affected := map[Hex]bool{}
+	for chunk := range hp.dirty {
+		affected[chunk] = true
+		for _, neighbor := range GetNeighbors(chunk) {
+			if _, ok := hp.clusters[neighbor]; ok {
+				affected[neighbor] = true
+				hp.connect(chunk, neighbor)
+			}
+		}
+	}
+	for chunk := range affected {
+		hp.link(chunk)
+	}
+	clear(hp.dirty)
+}
+
+// canStep reports whether a unit may step between two adjacent hexes.
+func (hp *HierarchicalPathfinder) canStep(from, to Hex) bool {
+	if !hp.isWalkable(to) {
+		return false
+	}
+	if hp.isEdgeBlocked == nil {
+		return true
+	}
+	edge, _ := EdgeBetween(from, to)
+	return !hp.isEdgeBlocked(edge)
+}
+
+// connect finds the open stretches of the border between two chunks and
+// keeps the middle crossing of each as the entrance. Crossings belong to
+// the same stretch when a unit can sidestep between them on both sides of
+// the border, which keeps every crossing reachable from its entrance.
+func (hp *HierarchicalPathfinder) connect(chunk, neighbor Hex) {
+	var open []crossing
+	for _, h := range hp.clusters[chunk].hexes {
+		if !hp.isWalkable(h) {
+			continue
+		}
+		for _, dir := range directions {
+			other := h.Add(dir)
+			if hp.chunkOf(other) == neighbor && hp.canStep(h, other) {
+				open = append(open, crossing{inside: h, outside: other})
+			}
+		}
+	}
+
+	beside := func(a, b Hex) bool {
+		return a == b || HexDistance(a, b) == 1 && hp.canStep(a, b)
+	}
+	var entrances []crossing
+	assigned := make([]bool, len(open))
+	for i := range open {
+		if assigned[i] {
+			continue
+		}
+		assigned[i] = true
+		stretch := []crossing{open[i]}
+		for j := 0; j < len(stretch); j++ {
+			for k, x := range open {
+				if !assigned[k] && beside(stretch[j].inside, x.inside) && beside(stretch[j].outside, x.outside) {
+					assigned[k] = true
+					stretch = append(stretch, x)
+				}
+			}
+		}
+		slices.SortFunc(stretch, func(a, b crossing) int {
+			if c := compareDrawOrder(a.inside, b.inside); c != 0 {
+				return c
+			}
+			return compareDrawOrder(a.outside, b.outside)
+		})
+		entrances = append(entrances, stretch[len(stretch)/2])
+	}
+
+	mirrored := make([]crossing, len(entrances))
+	for i, e := range entrances {
+		mirrored[i] = crossing{inside: e.outside, outside: e.inside}
+	}
+	hp.clusters[chunk].entrances[neighbor] = entrances
+	hp.clusters[neighbor].entrances[chunk] = mirrored
+}
+
+// link rebuilds a chunk's part of the entrance graph: a link across the
+// border for every entrance, and a link between every pair of entrance hexes
+// that can reach each other without leaving the chunk.
+func (hp *HierarchicalPathfinder) link(chunk Hex) {
+	c := hp.clusters[chunk]
+	c.nodes = c.nodes[:0]
+	c.links = map[Hex][]entranceLink{}
+	// Walk the neighbors in a fixed order so equally cheap routes are
+	// chosen the same way on every run.
+	neighbors := slices.SortedFunc(maps.Keys(c.entrances), compareDrawOrder)
+	for _, neighbor := range neighbors {
+		for _, e := range c.entrances[neighbor] {
+			c.nodes = append(c.nodes, e.inside)
+			c.links[e.inside] = append(c.links[e.inside], entranceLink{to: e.outside, cost: hp.cost(e.inside, e.outside)})
+		}
+	}
+	slices.SortFunc(c.nodes, compareDrawOrder)
+	c.nodes = slices.Compact(c.nodes)
+
+	for _, from := range c.nodes {
+		for _, to := range c.nodes {
+			if from == to {
+				continue
+			}
+			if _, cost, ok := hp.local(from, to, chunk); ok {
+				c.links[from] = append(c.links[from], entranceLink{to: to, cost: cost})
+			}
+		}
+	}
+}
+
+// local searches from start to goal without leaving chunk.
+func (hp *HierarchicalPathfinder) local(start, goal, chunk Hex) ([]Hex, int, bool) {
+	canStep := func(from, to Hex) bool {
+		return hp.chunkOf(to) == chunk && hp.canStep(from, to)
+	}
+	result := hp.finder.search(context.Background(), start, goal, canStep, hp.cost, hp.minCost, SearchLimits{})
+	if !result.Found() {
+		return nil, 0, false
+	}
+	return slices.Clone(result.Path), result.Cost, true
+}
+
+// FindPath returns a path from start to goal and its total cost, or nil and
+// 0 when the goal cannot be reached. It first rebuilds any chunks
+// invalidated since the last call.
+func (hp *HierarchicalPathfinder) FindPath(start, goal Hex) ([]Hex, int) {
+	hp.refresh()
+
+	startChunk, goalChunk := hp.chunkOf(start), hp.chunkOf(goal)
+	startCluster, ok := hp.clusters[startChunk]
+	goalCluster, goalOK := hp.clusters[goalChunk]
+	if !ok || !goalOK || !hp.isWalkable(goal) {
+		return nil, 0
+	}
+	if startChunk == goalChunk {
+		if path, cost, ok := hp.local(start, goal, startChunk); ok {
+			return path, cost
+		}
+	}
+
+	// Join start and goal to the entrances of their chunks for this query
+	// only.
+	startLinks := slices.Clone(startCluster.links[start])
+	for _, node := range startCluster.nodes {
+		if node == start {
+			continue
+		}
+		if _, cost, ok := hp.local(start, node, startChunk); ok {
+			startLinks = append(startLinks, entranceLink{to: node, cost: cost})
+		}
+	}
+	toGoal := map[Hex]int{}
+	for _, node := range goalCluster.nodes {
+		if node == goal {
+			continue
+		}
+		if _, cost, ok := hp.local(node, goal, goalChunk); ok {
+			toGoal[node] = cost
+		}
+	}
+
+	route := hp.searchEntrances(start, goal, startLinks, toGoal)
+	if route == nil {
+		return nil, 0
+	}
+	return hp.refine(route)
+}
+
+// searchEntrances runs A* over the entrance graph and returns the hexes it
+// passes through, or nil if goal cannot be reached.
+func (hp *HierarchicalPathfinder) searchEntrances(start, goal Hex, startLinks []entranceLink, toGoal map[Hex]int) []Hex {
+	openSet := &PriorityQueue{}
+	closedSet := make(map[Hex]bool)
+	cameFrom := make(map[Hex]Hex)
+	gScore := map[Hex]int{start: 0}
+	heap.Init(openSet)
+	heap.Push(openSet, &PathNode{hex: start, fScore: 0})
+
+	for openSet.Len() > 0 {
+		current := heap.Pop(openSet).(*PathNode).hex
+		if current == goal {
+			return reconstructPath(cameFrom, current)
+		}
+		if closedSet[current] {
+			continue
+		}
+		closedSet[current] = true
+
+		links := startLinks
+		if current != start {
+			links = hp.clusters[hp.chunkOf(current)].links[current]
+		}
+		if cost, ok := toGoal[current]; ok {
+			links = append(slices.Clip(links), entranceLink{to: goal, cost: cost})
+		}
+		for _, link := range links {
+			if closedSet[link.to] {
+				continue
+			}
+			tentativeGScore := gScore[current] + link.cost
+			if g, exists := gScore[link.to]; !exists || tentativeGScore < g {
+				cameFrom[link.to] = current
+				gScore[link.to] = tentativeGScore
+				fScore := tentativeGScore + int(HexDistance(link.to, goal))*hp.minCost
+				heap.Push(openSet, &PathNode{hex: link.to, fScore: fScore})
+			}
+		}
+	}
+	return nil
+}
+
+// refine expands a route through the entrance graph into a hex-by-hex path.
+// Consecutive hexes in different chunks are neighbors across a border; the
+// rest are joined by a local search inside their chunk.
+func (hp *HierarchicalPathfinder) refine(route []Hex) ([]Hex, int) {
+	path := []Hex{route[0]}
+	total := 0
+	for i := 1; i < len(route); i++ {
+		from, to := route[i-1], route[i]
+		chunk := hp.chunkOf(from)
+		if chunk != hp.chunkOf(to) {
+			path = append(path, to)
+			total += hp.cost(from, to)
+			continue
+		}
+		leg, cost, ok := hp.local(from, to, chunk)
+		if !ok {
+			return nil, 0
+		}
+		path = append(path, leg[1:]...)
+		total += cost
+	}
+	return path, total
+}
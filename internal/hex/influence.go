@@ -0,0 +1,137 @@
+package hex
+
+import "slices"
+
+// Faction identifies a side whose units project influence.
+type Faction int
+
+// Falloff scales a unit's threat by how far a hex is from it, given the
+// radius the threat reaches. It returns 1 on the unit's own hex.
+type Falloff func(distance, radius int64) float64
+
+// LinearFalloff drops threat by an equal share every step, leaving a sliver
+// on the edge of the radius.
+func LinearFalloff(distance, radius int64) float64 {
+	return 1 - float64(distance)/float64(radius+1)
+}
+
+// ConstantFalloff keeps full threat out to the edge of the radius, as for a
+// ranged attacker that hits as hard far away as up close.
+func ConstantFalloff(distance, radius int64) float64 {
+	return 1
+}
+
+// InfluenceMap records the threat each faction's units project over a fixed
+// set of hexes. Threat falls off with HexDistance and does not reach hexes
+// the unit has no HasLineOfSight to. Rebuild it with Clear and Add when
+// units move.
+type InfluenceMap struct {
+	// Falloff shapes threat with distance. NewInfluenceMap sets
+	// LinearFalloff.
+	Falloff Falloff
+
+	bounds     map[Hex]bool
+	isBlocking func(Hex) bool
+	// factions is every faction with threat, sorted, so layers are always
+	// added up in the same order and give the same total to the last bit.
+	factions []Faction
+	sum      map[Faction]map[Hex]float64
+	max      map[Faction]map[Hex]float64
+}
+
+// NewInfluenceMap returns an empty influence map over hexes. isBlocking
+// decides line of sight, as for HasLineOfSight.
+func NewInfluenceMap(hexes []Hex, isBlocking func(Hex) bool) *InfluenceMap {
+	m := &InfluenceMap{
+		Falloff:    LinearFalloff,
+		bounds:     make(map[Hex]bool, len(hexes)),
+		isBlocking: isBlocking,
+	}
+	for _, h := range hexes {
+		m.bounds[h] = true
+	}
+	m.Clear()
+	return m
+}
+
+// Clear removes every unit's threat.
+func (m *InfluenceMap) Clear() {
+	m.factions = nil
+	m.sum = map[Faction]map[Hex]float64{}
+	m.max = map[Faction]map[Hex]float64{}
+}
+
+// Add projects the threat of a faction's unit standing on at. It is
+// strength on the unit's own hex and reaches every hex within radius it can
+// see, scaled by Falloff.
+func (m *InfluenceMap) Add(faction Faction, at Hex, strength float64, radius int64) {
+	if i, found := slices.BinarySearch(m.factions, faction); !found {
+		m.factions = slices.Insert(m.factions, i, faction)
+		m.sum[faction] = map[Hex]float64{}
+		m.max[faction] = map[Hex]float64{}
+	}
+	sum, peak := m.sum[faction], m.max[faction]
+	for _, h := range Range(at, radius) {
+		if !m.bounds[h] || !HasLineOfSight(at, h, m.isBlocking) {
+			continue
+		}
+		threat := strength * m.Falloff(HexDistance(at, h), radius)
+		if threat <= 0 {
+			continue
+		}
+		sum[h] += threat
+		peak[h] = max(peak[h], threat)
+	}
+}
+
+// Sum returns the total threat on h from the given factions' units, or from
+// every faction when none are given. It measures how much damage could
+// converge on a hex.
+func (m *InfluenceMap) Sum(h Hex, factions ...Faction) float64 {
+	total := 0.0
+	for _, faction := range m.factions {
+		if len(factions) == 0 || slices.Contains(factions, faction) {
+			total += m.sum[faction][h]
+		}
+	}
+	return total
+}
+
+// Max returns the strongest single unit's threat on h from the given
+// factions, or from every faction when none are given. It measures the
+// worst one hit a hex can take.
+func (m *InfluenceMap) Max(h Hex, factions ...Faction) float64 {
+	strongest := 0.0
+	for _, faction := range m.factions {
+		if len(factions) == 0 || slices.Contains(factions, faction) {
+			strongest = max(strongest, m.max[faction][h])
+		}
+	}
+	return strongest
+}
+
+// Threat returns the summed threat on h from every faction but own: how
+// dangerous the hex is for a unit of that faction.
+func (m *InfluenceMap) Threat(h Hex, own Faction) float64 {
+	total := 0.0
+	for _, faction := range m.factions {
+		if faction != own {
+			total += m.sum[faction][h]
+		}
+	}
+	return total
+}
+
+// Safest returns the destination in r with the least danger, preferring the
+// cheapest to reach on a tie, so a unit only moves when moving helps. Build
+// r with Reachable using the unit's moves as the budget, and pass a danger
+// such as an InfluenceMap's Threat for the unit's faction.
+func (r *Reach) Safest(danger func(Hex) float64) Hex {
+	safest, least := r.Origin, danger(r.Origin)
+	for _, h := range r.Destinations() {
+		if d := danger(h); d < least {
+			safest, least = h, d
+		}
+	}
+	return safest
+}
@@ -1,75 +1,147 @@
 package hex
 
 import (
+	"math"
+
 	hx "github.com/gojuno/go.hexgrid"
 	morton "github.com/gojuno/go.morton"
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// Orientation selects whether hexes have a flat side or a point at the top.
+type Orientation int
+
 const (
-	IsoScaleX = 1.0
-	IsoScaleY = 0.5
+	Flat Orientation = iota
+	Pointy
 )
 
-var (
-	OffsetX = 640.0
-	OffsetY = 360.0
-	HexSize = 40.0
+const (
+	DefaultOriginX   = 640.0
+	DefaultOriginY   = 360.0
+	DefaultHexSize   = 40.0
+	DefaultIsoScaleX = 1.0
+	DefaultIsoScaleY = 0.5
+	// DefaultHeightScale lifts a hex of height 1 by half its size on screen.
+	DefaultHeightScale = 0.5
 )
 
+// mortonCodec is only needed to satisfy hx.MakeGrid; it is never mutated, so
+// every grid shares it.
+var mortonCodec = morton.Make64(2, 32)
+
+// LayoutConfig describes how a Layout projects hexes onto the screen. Origin
+// is the pixel position of hex (0,0) and Size the hex radius before the
+// isometric scale is applied. HeightScale is how far up the screen one unit
+// of elevation lifts a hex, as a fraction of Size.
+type LayoutConfig struct {
+	Orientation Orientation
+	Size        float64
+	OriginX     float64
+	OriginY     float64
+	IsoScaleX   float64
+	IsoScaleY   float64
+	HeightScale float64
+}
+
+// DefaultLayoutConfig returns the flat-top isometric projection centered on
+// a 1280x720 screen.
+func DefaultLayoutConfig() LayoutConfig {
+	return LayoutConfig{
+		Orientation: Flat,
+		Size:        DefaultHexSize,
+		OriginX:     DefaultOriginX,
+		OriginY:     DefaultOriginY,
+		IsoScaleX:   DefaultIsoScaleX,
+		IsoScaleY:   DefaultIsoScaleY,
+		HeightScale: DefaultHeightScale,
+	}
+}
+
+// Layout converts between hex and screen coordinates. Every Layout owns its
+// configuration, so several can be used side by side (a main map and a
+// minimap, say) without interfering.
 type Layout struct {
+	config  LayoutConfig
 	hexgrid *hx.Grid
 }
 
 func NewLayout() *Layout {
-	center := hx.MakePoint(0, 0)
-	size := hx.MakePoint(HexSize, HexSize)
-	grid := hx.MakeGrid(hx.OrientationFlat, center, size, morton.Make64(2, 32))
-	return &Layout{
-		hexgrid: grid,
+	return NewLayoutWithConfig(DefaultLayoutConfig())
+}
+
+func NewLayoutWithConfig(config LayoutConfig) *Layout {
+	l := &Layout{config: config}
+	l.rebuild()
+	return l
+}
+
+// rebuild recreates the cached grid after the configuration changes. The
+// isometric scale is folded into the grid size so conversions need no extra
+// math.
+func (l *Layout) rebuild() {
+	orientation := hx.OrientationFlat
+	if l.config.Orientation == Pointy {
+		orientation = hx.OrientationPointy
 	}
+	origin := hx.MakePoint(l.config.OriginX, l.config.OriginY)
+	size := hx.MakePoint(l.config.Size*l.config.IsoScaleX, l.config.Size*l.config.IsoScaleY)
+	l.hexgrid = hx.MakeGrid(orientation, origin, size, mortonCodec)
 }
 
-func (l *Layout) HexToPixel(q, r int64) (float64, float64) {
-	currentGrid := hx.MakeGrid(hx.OrientationFlat, hx.MakePoint(0, 0), hx.MakePoint(HexSize, HexSize), morton.Make64(2, 32))
-	hex := hx.MakeHex(q, r)
+func (l *Layout) Config() LayoutConfig {
+	return l.config
+}
 
-	point := currentGrid.HexCenter(hex)
+func (l *Layout) SetConfig(config LayoutConfig) {
+	l.config = config
+	l.rebuild()
+}
 
-	x := point.X()*IsoScaleX + OffsetX
-	y := point.Y()*IsoScaleY + OffsetY
+func (l *Layout) Size() float64 {
+	return l.config.Size
+}
 
-	return x, y
+func (l *Layout) SetSize(size float64) {
+	l.config.Size = size
+	l.rebuild()
 }
 
-func (l *Layout) PixelToHex(x, y float64) (int64, int64) {
-	currentGrid := hx.MakeGrid(hx.OrientationFlat, hx.MakePoint(0, 0), hx.MakePoint(HexSize, HexSize), morton.Make64(2, 32))
-	x -= OffsetX
-	y -= OffsetY
+func (l *Layout) Origin() (float64, float64) {
+	return l.config.OriginX, l.config.OriginY
+}
 
-	x /= IsoScaleX
-	y /= IsoScaleY
+func (l *Layout) SetOrigin(x, y float64) {
+	l.config.OriginX = x
+	l.config.OriginY = y
+	l.rebuild()
+}
 
-	point := hx.MakePoint(x, y)
-	hex := currentGrid.HexAt(point)
+func (l *Layout) HexToPixel(q, r int64) (float64, float64) {
+	point := l.hexgrid.HexCenter(hx.MakeHex(q, r))
+	return point.X(), point.Y()
+}
 
+func (l *Layout) PixelToHex(x, y float64) (int64, int64) {
+	hex := l.hexgrid.HexAt(hx.MakePoint(x, y))
 	return hex.Q(), hex.R()
 }
 
 func (l *Layout) GetCorners(q, r int64) []ebiten.Vertex {
-	currentGrid := hx.MakeGrid(hx.OrientationFlat, hx.MakePoint(0, 0), hx.MakePoint(HexSize, HexSize), morton.Make64(2, 32))
-	hex := hx.MakeHex(q, r)
-	corners := currentGrid.HexCorners(hex)
+	return l.GetCornersAt(q, r, 0)
+}
+
+// GetCornersAt returns the corners of the top face of a hex raised to height.
+func (l *Layout) GetCornersAt(q, r int64, height float64) []ebiten.Vertex {
+	corners := l.hexgrid.HexCorners(hx.MakeHex(q, r))
+	lift := l.heightOffset(height)
 
 	vertices := make([]ebiten.Vertex, 6)
 
 	for i, corner := range corners {
-		x := corner.X()*IsoScaleX + OffsetX
-		y := corner.Y()*IsoScaleY + OffsetY
-
 		vertices[i] = ebiten.Vertex{
-			DstX:   float32(x),
-			DstY:   float32(y),
+			DstX:   float32(corner.X()),
+			DstY:   float32(corner.Y() - lift),
 			SrcX:   0, // Will be used when we add textures
 			SrcY:   0,
 			ColorR: 1,
@@ -83,58 +155,153 @@ func (l *Layout) GetCorners(q, r int64) []ebiten.Vertex {
 	return vertices
 }
 
+// VertexToPixel returns the screen position of a hex corner.
+func (l *Layout) VertexToPixel(v Vertex) (float64, float64) {
+	corners := l.hexgrid.HexCorners(hx.MakeHex(v.Hex.Q, v.Hex.R))
+	corner := corners[l.cornerIndex(v.Corner)]
+	return corner.X(), corner.Y()
+}
+
+// EdgeToPixels returns the screen positions of both ends of an edge.
+func (l *Layout) EdgeToPixels(e Edge) (x1, y1, x2, y2 float64) {
+	a, b := e.Vertices()
+	x1, y1 = l.VertexToPixel(a)
+	x2, y2 = l.VertexToPixel(b)
+	return x1, y1, x2, y2
+}
+
+// cornerIndex maps a Vertex corner number to the index GetCorners uses for
+// the same corner. GetCorners runs clockwise on screen starting at 0° (flat)
+// or 30° (pointy), while corners run counter-clockwise from between
+// directions 0 and 1.
+func (l *Layout) cornerIndex(corner int) int {
+	if l.config.Orientation == Pointy {
+		return ((5-corner)%6 + 6) % 6
+	}
+	return ((6-corner)%6 + 6) % 6
+}
+
 func (l *Layout) ZoomIn() {
-	HexSize *= 1.1
+	l.SetSize(l.config.Size * 1.1)
 }
 
 func (l *Layout) ZoomOut() {
-	HexSize /= 1.1
+	l.SetSize(l.config.Size / 1.1)
 }
 
+// GetVisibleHexes returns the hexes within maxRange that origin can see.
+// It shadowcasts with OcclusionCenter; see FieldOfView for other rules.
 func GetVisibleHexes(origin Hex, maxRange int64, isBlocking func(Hex) bool) []Hex {
-	visibleHexes := []Hex{}
+	return FieldOfView(origin, maxRange, isBlocking, OcclusionCenter)
+}
 
-	for q := origin.Q - maxRange; q <= origin.Q+maxRange; q++ {
-		for r := origin.R - maxRange; r <= origin.R+maxRange; r++ {
-			target := Hex{Q: q, R: r}
-			if HexDistance(origin, target) > maxRange {
-				continue
-			}
+// LineEpsilon is how far HexLine nudges its samples off the exact line so
+// points that land on a hex edge round the same way every time.
+const LineEpsilon = 1e-6
 
-			if HasLineOfSight(origin, target, isBlocking) {
-				visibleHexes = append(visibleHexes, target)
-			}
-		}
-	}
+// LineOfSightMode selects how edge ties are resolved when casting a line.
+type LineOfSightMode int
 
-	return visibleHexes
-}
+const (
+	// LineOfSightNudged casts a single line nudged by +LineEpsilon. It gives
+	// the same answer from either end, but a line running exactly along a
+	// hex edge always resolves toward the same side of that edge.
+	LineOfSightNudged LineOfSightMode = iota
+	// LineOfSightSymmetric casts the line nudged both ways and succeeds if
+	// either is clear, so edge-grazing lines are only blocked when the hexes
+	// on both sides of the edge block.
+	LineOfSightSymmetric
+)
 
+// HasLineOfSight reports whether nothing blocks the line between from and to.
+// The endpoints themselves are never tested, so the result does not depend on
+// which end the line is cast from.
 func HasLineOfSight(from, to Hex, isBlocking func(Hex) bool) bool {
-	line := HexLine(from, to)
+	return LineOfSight(from, to, isBlocking, LineOfSightSymmetric)
+}
+
+// LineOfSight reports whether nothing blocks the line between from and to,
+// with mode choosing how a line that runs along a hex edge is resolved:
+// LineOfSightNudged tests one side of the edge, LineOfSightSymmetric is clear
+// if either side is. HasLineOfSight is LineOfSight in symmetric mode, and
+// LineOfSightWithEdges adds walls on hex borders.
+func LineOfSight(from, to Hex, isBlocking func(Hex) bool, mode LineOfSightMode) bool {
+	return LineOfSightWithEdges(from, to, isBlocking, nil, mode)
+}
+
+// HasLineOfSightWithEdges is HasLineOfSight on a map with walls on hex
+// borders: the line is also blocked when it crosses an edge for which
+// isEdgeBlocked returns true.
+func HasLineOfSightWithEdges(from, to Hex, isBlocking func(Hex) bool, isEdgeBlocked func(Edge) bool) bool {
+	return LineOfSightWithEdges(from, to, isBlocking, isEdgeBlocked, LineOfSightSymmetric)
+}
+
+// LineOfSightWithEdges is LineOfSight with an optional edge-blocking
+// callback; isEdgeBlocked may be nil.
+func LineOfSightWithEdges(from, to Hex, isBlocking func(Hex) bool, isEdgeBlocked func(Edge) bool, mode LineOfSightMode) bool {
+	if lineIsClear(HexLineNudged(from, to, LineEpsilon), isBlocking, isEdgeBlocked) {
+		return true
+	}
+	if mode == LineOfSightSymmetric {
+		return lineIsClear(HexLineNudged(from, to, -LineEpsilon), isBlocking, isEdgeBlocked)
+	}
+	return false
+}
 
-	// Check each hex along the line
-	for _, h := range line {
-		if h == to {
-			return true // reached target
+func lineIsClear(line []Hex, isBlocking func(Hex) bool, isEdgeBlocked func(Edge) bool) bool {
+	for i := 1; i < len(line); i++ {
+		if i < len(line)-1 && isBlocking(line[i]) {
+			return false
 		}
-		if isBlocking(h) {
-			return false // blocked before reaching target
+		if isEdgeBlocked != nil {
+			if edge, _ := EdgeBetween(line[i-1], line[i]); isEdgeBlocked(edge) {
+				return false
+			}
 		}
 	}
 	return true
 }
 
+// HexLine returns the hexes on the straight line from one hex to another,
+// including both ends, nudged by LineEpsilon to break ties consistently.
 func HexLine(from, to Hex) []Hex {
+	return HexLineNudged(from, to, LineEpsilon)
+}
+
+// HexLineNudged interpolates between the two hex centers in cube space and
+// rounds each sample to the nearest hex. Both ends are first shifted by
+// (nudge, 2*nudge, -3*nudge) in cube coordinates so no two components can tie;
+// pass 0 for the raw line.
+func HexLineNudged(from, to Hex, nudge float64) []Hex {
 	distance := HexDistance(from, to)
-	results := []Hex{}
+	if distance == 0 {
+		return []Hex{from}
+	}
+
+	fromQ, fromR := float64(from.Q)+nudge, float64(from.R)+2*nudge
+	toQ, toR := float64(to.Q)+nudge, float64(to.R)+2*nudge
 
-	for i := 0; i <= int(distance); i++ {
+	results := make([]Hex, 0, distance+1)
+	for i := int64(0); i <= distance; i++ {
 		t := float64(i) / float64(distance)
-		q := from.Q + int64(float64(to.Q-from.Q)*t)
-		r := from.R + int64(float64(to.R-from.R)*t)
-		results = append(results, Hex{Q: q, R: r})
+		results = append(results, cubeRound(fromQ+(toQ-fromQ)*t, fromR+(toR-fromR)*t))
 	}
 
 	return results
 }
+
+// cubeRound rounds fractional axial coordinates to the nearest hex, fixing up
+// the component with the largest rounding error so q+r+s stays zero.
+func cubeRound(fq, fr float64) Hex {
+	fs := -fq - fr
+	q, r, s := math.Round(fq), math.Round(fr), math.Round(fs)
+
+	dq, dr, ds := math.Abs(q-fq), math.Abs(r-fr), math.Abs(s-fs)
+	if dq > dr && dq > ds {
+		q = -r - s
+	} else if dr > ds {
+		r = -q - s
+	}
+
+	return Hex{Q: int64(q), R: int64(r)}
+}
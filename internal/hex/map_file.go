@@ -0,0 +1,455 @@
+package hex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// MapFileVersion is the version of the map file format WriteJSON and
+// WriteBinary produce. Bump it, and add a Migrations entry, whenever a change
+// means older files need upgrading.
+const MapFileVersion = 1
+
+// Shape kinds recorded in a map file, naming the constructor a map was
+// built with.
+const (
+	ShapeCustom    = "custom"
+	ShapeHexagon   = "hexagon"
+	ShapeRectangle = "rectangle"
+	ShapeTriangle  = "triangle"
+)
+
+var (
+	// ErrUnsupportedVersion is returned when reading a file written by a
+	// newer version of the format than this build knows.
+	ErrUnsupportedVersion = errors.New("map file version is not supported")
+	// ErrNotMapFile is returned when binary data is not a map file or is cut
+	// short.
+	ErrNotMapFile = errors.New("not a valid binary map file")
+	// ErrInvalidMapFile is returned when a file decodes but describes a map
+	// that cannot be loaded, such as one with a spawn off the map.
+	ErrInvalidMapFile = errors.New("invalid map file")
+)
+
+// Migrations upgrade files written by older versions of the format.
+// Migrations[v] turns a file of version v into one of version v+1, and
+// reading a file applies them in turn until it is current. Older files are
+// decoded into today's MapFile first, JSON by field name and binary with the
+// layout of the version that wrote it, so a migration fills in or converts
+// whatever its version left out.
+var Migrations = map[int]func(*MapFile) error{}
+
+// MapFile is a hex map as saved to disk: the shape, every tile's terrain and
+// elevation, features on the edges between hexes, spawn points and free-form
+// metadata. It can be written as indented JSON, for authoring by hand and
+// tools outside the game, or as a compact binary.
+type MapFile struct {
+	Version  int               `json:"version"`
+	Shape    Shape             `json:"shape"`
+	Tiles    []Tile            `json:"tiles"`
+	Edges    []EdgeFeature     `json:"edges,omitempty"`
+	Spawns   []Spawn           `json:"spawns,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Shape records how a map was laid out. The hexes themselves are always
+// listed in Tiles; Kind and the sizes tell tools which constructor gives the
+// same map, and Orientation how to draw it.
+type Shape struct {
+	Kind        string       `json:"kind"`
+	Orientation Orientation  `json:"orientation"`
+	System      OffsetSystem `json:"system,omitempty"`
+	Width       int64        `json:"width,omitempty"`
+	Height      int64        `json:"height,omitempty"`
+	Radius      int64        `json:"radius,omitempty"`
+}
+
+// Tile is what a map file stores for one hex. Terrain is a name such as
+// "forest", so files stay readable and do not depend on the order of the
+// game's terrain constants.
+type Tile struct {
+	Hex       Hex     `json:"hex"`
+	Terrain   string  `json:"terrain"`
+	Elevation float64 `json:"elevation,omitempty"`
+}
+
+// EdgeFeature is a named feature, such as "wall" or "door", on the border
+// between two hexes.
+type EdgeFeature struct {
+	Edge    Edge   `json:"edge"`
+	Feature string `json:"feature"`
+}
+
+// Spawn is a named point units start on.
+type Spawn struct {
+	Name string `json:"name"`
+	Hex  Hex    `json:"hex"`
+}
+
+// NewMapFile saves the hexes of m, in draw order, turning each value into a
+// terrain name and elevation with tile. Fill in the shape's kind and sizes,
+// the edges, spawns and metadata afterwards.
+func NewMapFile[T any](m *Map[T], tile func(T) (terrain string, elevation float64)) *MapFile {
+	f := &MapFile{
+		Version: MapFileVersion,
+		Shape:   Shape{Kind: ShapeCustom, Orientation: m.Orientation()},
+		Tiles:   make([]Tile, 0, m.Len()),
+	}
+	for h, v := range m.All() {
+		terrain, elevation := tile(v)
+		f.Tiles = append(f.Tiles, Tile{Hex: h, Terrain: terrain, Elevation: elevation})
+	}
+	return f
+}
+
+// MapFromFile builds a map over the file's tiles, with the file's
+// orientation, turning each tile into a value with value.
+func MapFromFile[T any](f *MapFile, value func(Tile) T) *Map[T] {
+	hexes := make([]Hex, len(f.Tiles))
+	for i, tile := range f.Tiles {
+		hexes[i] = tile.Hex
+	}
+	m := NewOrientedMap[T](hexes, f.Shape.Orientation)
+	for _, tile := range f.Tiles {
+		m.Set(tile.Hex, value(tile))
+	}
+	return m
+}
+
+// WriteJSON writes the file as indented JSON at the current version.
+func (f *MapFile) WriteJSON(w io.Writer) error {
+	current := *f
+	current.Version = MapFileVersion
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&current)
+}
+
+// ReadMapFileJSON reads a file written by WriteJSON, migrating it to the
+// current version.
+func ReadMapFileJSON(r io.Reader) (*MapFile, error) {
+	f := &MapFile{}
+	if err := json.NewDecoder(r).Decode(f); err != nil {
+		return nil, err
+	}
+	if err := f.migrate(); err != nil {
+		return nil, err
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// mapFileMagic starts every binary map file.
+var mapFileMagic = []byte("HEXM")
+
+// WriteBinary writes the file in the compact binary format at the current
+// version. Numbers are varints, hexes are stored as the step from the one
+// before, and terrain and feature names are written once in a string table
+// and referred to by index.
+func (f *MapFile) WriteBinary(w io.Writer) error {
+	strs := []string{}
+	index := map[string]uint64{}
+	intern := func(s string) uint64 {
+		i, ok := index[s]
+		if !ok {
+			i = uint64(len(strs))
+			index[s] = i
+			strs = append(strs, s)
+		}
+		return i
+	}
+	for _, tile := range f.Tiles {
+		intern(tile.Terrain)
+	}
+	for _, edge := range f.Edges {
+		intern(edge.Feature)
+	}
+
+	buf := slices.Clone(mapFileMagic)
+	buf = binary.AppendUvarint(buf, MapFileVersion)
+	buf = appendString(buf, f.Shape.Kind)
+	buf = binary.AppendUvarint(buf, uint64(f.Shape.Orientation))
+	buf = binary.AppendUvarint(buf, uint64(f.Shape.System))
+	buf = binary.AppendVarint(buf, f.Shape.Width)
+	buf = binary.AppendVarint(buf, f.Shape.Height)
+	buf = binary.AppendVarint(buf, f.Shape.Radius)
+
+	buf = binary.AppendUvarint(buf, uint64(len(strs)))
+	for _, s := range strs {
+		buf = appendString(buf, s)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(f.Tiles)))
+	prev := Hex{}
+	for _, tile := range f.Tiles {
+		buf = appendStep(buf, prev, tile.Hex)
+		buf = binary.AppendUvarint(buf, index[tile.Terrain])
+		// Byte-reversed, as gob does, so round numbers take few bytes.
+		buf = binary.AppendUvarint(buf, bits.ReverseBytes64(math.Float64bits(tile.Elevation)))
+		prev = tile.Hex
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(f.Edges)))
+	prev = Hex{}
+	for _, edge := range f.Edges {
+		buf = appendStep(buf, prev, edge.Edge.Hex)
+		buf = binary.AppendUvarint(buf, uint64(edge.Edge.Side))
+		buf = binary.AppendUvarint(buf, index[edge.Feature])
+		prev = edge.Edge.Hex
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(f.Spawns)))
+	for _, spawn := range f.Spawns {
+		buf = appendString(buf, spawn.Name)
+		buf = binary.AppendVarint(buf, spawn.Hex.Q)
+		buf = binary.AppendVarint(buf, spawn.Hex.R)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(f.Metadata)))
+	for _, key := range slices.Sorted(maps.Keys(f.Metadata)) {
+		buf = appendString(buf, key)
+		buf = appendString(buf, f.Metadata[key])
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadMapFileBinary reads a file written by WriteBinary, by this version or
+// any older one, migrating it to the current version.
+func ReadMapFileBinary(r io.Reader) (*MapFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, mapFileMagic) {
+		return nil, ErrNotMapFile
+	}
+	d := &mapFileDecoder{data: data[len(mapFileMagic):]}
+
+	version := int(d.uvarint())
+	if d.err != nil {
+		return nil, d.err
+	}
+	if version > MapFileVersion {
+		return nil, fmt.Errorf("%w: %d is newer than %d", ErrUnsupportedVersion, version, MapFileVersion)
+	}
+	decode, ok := binaryLayouts[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: no binary layout for %d", ErrUnsupportedVersion, version)
+	}
+	f := decode(d)
+	if d.err == nil && len(d.data) > 0 {
+		d.fail()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	f.Version = version
+
+	if err := f.migrate(); err != nil {
+		return nil, err
+	}
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// binaryLayouts decode the body of a binary file, after the magic and
+// version, for each version whose layout WriteBinary once wrote. When the
+// layout changes, keep the old decoder under its version so old files still
+// read, and let Migrations upgrade what it returns.
+var binaryLayouts = map[int]func(*mapFileDecoder) *MapFile{
+	1: (*mapFileDecoder).mapFileV1,
+}
+
+// mapFileV1 decodes the layout WriteBinary writes at version 1.
+func (d *mapFileDecoder) mapFileV1() *MapFile {
+	f := &MapFile{}
+	f.Shape = Shape{
+		Kind:        d.string(),
+		Orientation: Orientation(d.uvarint()),
+		System:      OffsetSystem(d.uvarint()),
+		Width:       d.varint(),
+		Height:      d.varint(),
+		Radius:      d.varint(),
+	}
+
+	strs := make([]string, d.count())
+	for i := range strs {
+		strs[i] = d.string()
+	}
+	lookup := func() string {
+		i := d.uvarint()
+		if i >= uint64(len(strs)) {
+			d.fail()
+			return ""
+		}
+		return strs[i]
+	}
+
+	f.Tiles = make([]Tile, d.count())
+	prev := Hex{}
+	for i := range f.Tiles {
+		prev = d.step(prev)
+		f.Tiles[i] = Tile{
+			Hex:       prev,
+			Terrain:   lookup(),
+			Elevation: math.Float64frombits(bits.ReverseBytes64(d.uvarint())),
+		}
+	}
+
+	if n := d.count(); n > 0 {
+		f.Edges = make([]EdgeFeature, n)
+		prev = Hex{}
+		for i := range f.Edges {
+			prev = d.step(prev)
+			f.Edges[i] = EdgeFeature{Edge: Edge{Hex: prev, Side: Direction(d.uvarint())}, Feature: lookup()}
+		}
+	}
+
+	if n := d.count(); n > 0 {
+		f.Spawns = make([]Spawn, n)
+		for i := range f.Spawns {
+			f.Spawns[i] = Spawn{Name: d.string(), Hex: Hex{Q: d.varint(), R: d.varint()}}
+		}
+	}
+
+	if n := d.count(); n > 0 {
+		f.Metadata = make(map[string]string, n)
+		for range n {
+			key := d.string()
+			f.Metadata[key] = d.string()
+		}
+	}
+	return f
+}
+
+// migrate runs the Migrations that bring the file up to MapFileVersion.
+func (f *MapFile) migrate() error {
+	if f.Version > MapFileVersion {
+		return fmt.Errorf("%w: %d is newer than %d", ErrUnsupportedVersion, f.Version, MapFileVersion)
+	}
+	for f.Version < MapFileVersion {
+		migration, ok := Migrations[f.Version]
+		if !ok {
+			return fmt.Errorf("%w: no migration from %d", ErrUnsupportedVersion, f.Version)
+		}
+		if err := migration(f); err != nil {
+			return fmt.Errorf("migrating map file from version %d: %w", f.Version, err)
+		}
+		f.Version++
+	}
+	return nil
+}
+
+// validate rejects files that would not load into a Map or draw as saved:
+// an unknown orientation or offset system, repeated hexes, edges not stored
+// the canonical way, and edges or spawns off the map.
+func (f *MapFile) validate() error {
+	if f.Shape.Orientation != Flat && f.Shape.Orientation != Pointy {
+		return fmt.Errorf("%w: unknown orientation %d", ErrInvalidMapFile, f.Shape.Orientation)
+	}
+	if f.Shape.System < OddQ || f.Shape.System > EvenR {
+		return fmt.Errorf("%w: unknown offset system %d", ErrInvalidMapFile, f.Shape.System)
+	}
+	onMap := make(map[Hex]bool, len(f.Tiles))
+	for _, tile := range f.Tiles {
+		if onMap[tile.Hex] {
+			return fmt.Errorf("%w: %v is listed twice", ErrInvalidMapFile, tile.Hex)
+		}
+		onMap[tile.Hex] = true
+	}
+	for _, edge := range f.Edges {
+		if edge.Edge.Side < 0 || edge.Edge.Side > 2 {
+			return fmt.Errorf("%w: edge of %v has side %d, want 0, 1 or 2", ErrInvalidMapFile, edge.Edge.Hex, edge.Edge.Side)
+		}
+		// An edge on the rim of the map has only one side on it.
+		if a, b := edge.Edge.Hexes(); !onMap[a] && !onMap[b] {
+			return fmt.Errorf("%w: edge %v is off the map", ErrInvalidMapFile, edge.Edge)
+		}
+	}
+	for _, spawn := range f.Spawns {
+		if !onMap[spawn.Hex] {
+			return fmt.Errorf("%w: spawn %q at %v is off the map", ErrInvalidMapFile, spawn.Name, spawn.Hex)
+		}
+	}
+	return nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendStep(buf []byte, from, to Hex) []byte {
+	buf = binary.AppendVarint(buf, to.Q-from.Q)
+	return binary.AppendVarint(buf, to.R-from.R)
+}
+
+// mapFileDecoder reads the binary format. The first error sticks, and every
+// read after it returns zero values, so the reader only checks once.
+type mapFileDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *mapFileDecoder) fail() {
+	if d.err == nil {
+		d.err = ErrNotMapFile
+	}
+	d.data = nil
+}
+
+func (d *mapFileDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *mapFileDecoder) varint() int64 {
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// count reads the length of a list. Every entry takes at least a byte, so a
+// count longer than what is left is an error rather than a huge allocation.
+func (d *mapFileDecoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *mapFileDecoder) string() string {
+	n := d.count()
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+func (d *mapFileDecoder) step(from Hex) Hex {
+	return Hex{Q: from.Q + d.varint(), R: from.R + d.varint()}
+}
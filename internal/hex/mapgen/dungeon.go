@@ -0,0 +1,515 @@
+package mapgen
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+// DungeonTile is what fills a hex of a dungeon.
+type DungeonTile int
+
+const (
+	Rock DungeonTile = iota
+	RoomFloor
+	CorridorFloor
+)
+
+func (t DungeonTile) String() string {
+	switch t {
+	case Rock:
+		return "rock"
+	case RoomFloor:
+		return "room"
+	default:
+		return "corridor"
+	}
+}
+
+// RoomKind is the part a room plays in an encounter.
+type RoomKind int
+
+const (
+	EncounterRoom RoomKind = iota
+	StartRoom
+	BossRoom
+	TreasureRoom
+)
+
+func (k RoomKind) String() string {
+	switch k {
+	case EncounterRoom:
+		return "encounter"
+	case StartRoom:
+		return "start"
+	case BossRoom:
+		return "boss"
+	default:
+		return "treasure"
+	}
+}
+
+// Room is one room of a dungeon. Links holds the IDs of the rooms that can
+// be walked to from this one through corridors without crossing another
+// room, in increasing order, which makes the rooms and their links the
+// dungeon's room graph.
+type Room struct {
+	ID     int
+	Kind   RoomKind
+	Center hex.Hex
+	Hexes  []hex.Hex
+	Links  []int
+}
+
+// Corridor is a passage dug between two rooms. Corridors may share hexes
+// where they cross. Doors are the edges where it meets the rooms.
+type Corridor struct {
+	Rooms [2]int
+	Hexes []hex.Hex
+	Doors []hex.Edge
+}
+
+// Dungeon is a generated dungeon. Tiles covers the whole rectangle and is
+// drawn like any other map; rooms are walled off from the corridors except
+// at their doors, so path searches need IsEdgeBlocked as well as Walkable.
+type Dungeon struct {
+	Tiles     *hex.Map[DungeonTile]
+	Rooms     []Room
+	Corridors []Corridor
+
+	roomAt map[hex.Hex]int
+	doors  map[hex.Edge]bool
+}
+
+// DungeonConfig controls the dungeon generator. Start from
+// DefaultDungeonConfig and set a Seed.
+type DungeonConfig struct {
+	Seed uint64
+	// Width and Height size the map as for hex.NewRectangularMap.
+	Width, Height int64
+	// Rooms is how many rooms to try to place. Fewer fit on crowded maps.
+	Rooms                        int
+	MinRoomRadius, MaxRoomRadius int64
+	// ExtraCorridors adds loops on top of the corridors that are needed to
+	// connect every room, so parties have more than one way round.
+	ExtraCorridors int
+	TreasureRooms  int
+}
+
+// DefaultDungeonConfig returns settings for a dungeon of about a dozen rooms.
+func DefaultDungeonConfig() DungeonConfig {
+	return DungeonConfig{
+		Width:          60,
+		Height:         40,
+		Rooms:          12,
+		MinRoomRadius:  2,
+		MaxRoomRadius:  4,
+		ExtraCorridors: 2,
+		TreasureRooms:  2,
+	}
+}
+
+// roomPlacementTries is how many random spots are tried for each room.
+const roomPlacementTries = 50
+
+// roomShapes build the varied room outlines around (0,0), given a radius.
+var roomShapes = []func(rng *rand.Rand, radius int64) []hex.Hex{
+	// Hexagonal hall
+	func(_ *rand.Rand, radius int64) []hex.Hex {
+		return hex.Range(hex.Hex{}, radius)
+	},
+	// Rhombus
+	func(_ *rand.Rand, radius int64) []hex.Hex {
+		return hex.NewParallelogramMap[bool](-radius, radius, -radius/2, radius/2).Hexes()
+	},
+	// Triangle, moved so its middle sits on (0,0)
+	func(_ *rand.Rand, radius int64) []hex.Hex {
+		size := radius * 3 / 2
+		return hex.Translation(hex.Hex{Q: -size / 3, R: -size / 3}).Apply(hex.NewTriangularMap[bool](size).Hexes())
+	},
+	// Cavern of two overlapping halls
+	func(rng *rand.Rand, radius int64) []hex.Hex {
+		offset := hex.Direction(rng.IntN(6)).Offset().Scale(radius)
+		return slices.Concat(hex.Range(hex.Hex{}, radius), hex.Range(offset, radius-1))
+	},
+	// Gallery, a long hall three hexes wide
+	func(_ *rand.Rand, radius int64) []hex.Hex {
+		end := hex.Hex{Q: radius + 1}
+		gallery := []hex.Hex{}
+		for _, h := range hex.HexLine(end.Scale(-1), end) {
+			gallery = append(gallery, hex.Range(h, 1)...)
+		}
+		return gallery
+	},
+}
+
+// GenerateDungeon places rooms, digs corridors between them, puts doors
+// where corridors meet rooms and tags the rooms. The same config always
+// gives the same dungeon. It fails when the map has no area, or when fewer
+// than two rooms fit or the rooms cannot all be connected; try another seed.
+func GenerateDungeon(config DungeonConfig) (*Dungeon, error) {
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, fmt.Errorf("dungeon size %dx%d is not positive", config.Width, config.Height)
+	}
+	rng := rand.New(rand.NewPCG(config.Seed, 0x64756e67656f6e))
+	d := &Dungeon{
+		Tiles:  hex.NewRectangularMap[DungeonTile](config.Width, config.Height),
+		roomAt: map[hex.Hex]int{},
+		doors:  map[hex.Edge]bool{},
+	}
+
+	d.placeRooms(rng, config)
+	if len(d.Rooms) < 2 {
+		return nil, errors.New("fewer than two rooms fit on the map")
+	}
+	d.digCorridors(rng, config.ExtraCorridors)
+	d.linkRooms()
+	if !d.connected() {
+		return nil, errors.New("rooms could not all be connected")
+	}
+	d.tagRooms(config.TreasureRooms)
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// placeRooms drops randomly shaped and turned rooms at random spots, keeping
+// a ring of rock around each room and along the edge of the map.
+func (d *Dungeon) placeRooms(rng *rand.Rand, config DungeonConfig) {
+	hexes := d.Tiles.Hexes()
+	minRadius := max(config.MinRoomRadius, 1)
+	maxRadius := max(config.MaxRoomRadius, minRadius)
+
+	for range config.Rooms {
+		for range roomPlacementTries {
+			radius := minRadius + rng.Int64N(maxRadius-minRadius+1)
+			shape := roomShapes[rng.IntN(len(roomShapes))](rng, radius)
+			center := hexes[rng.IntN(len(hexes))]
+			room := hex.Rotation(hex.Hex{}, rng.IntN(6)).Then(hex.Translation(center)).Apply(shape)
+			slices.SortFunc(room, compareHexes)
+			room = slices.Compact(room)
+
+			if d.fits(room) {
+				d.addRoom(room)
+				break
+			}
+		}
+	}
+}
+
+// fits reports whether a room lies inside the map, off its edge, and at
+// least two hexes clear of every other room.
+func (d *Dungeon) fits(room []hex.Hex) bool {
+	for _, h := range room {
+		for _, near := range hex.Range(h, 2) {
+			if _, taken := d.roomAt[near]; taken {
+				return false
+			}
+		}
+		if !d.interior(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// interior reports whether h and all its neighbors are on the map, so
+// nothing can be dug along the map's edge.
+func (d *Dungeon) interior(h hex.Hex) bool {
+	return d.Tiles.Contains(h) && len(d.Tiles.Neighbors(h)) == 6
+}
+
+func (d *Dungeon) addRoom(hexes []hex.Hex) {
+	id := len(d.Rooms)
+	for _, h := range hexes {
+		d.roomAt[h] = id
+		d.Tiles.Set(h, RoomFloor)
+	}
+	d.Rooms = append(d.Rooms, Room{ID: id, Center: nearestToCentroid(hexes), Hexes: hexes})
+}
+
+// nearestToCentroid returns the hex of the set closest to its average
+// position, which unlike the average itself always lies inside the room.
+func nearestToCentroid(hexes []hex.Hex) hex.Hex {
+	var q, r int64
+	for _, h := range hexes {
+		q, r = q+h.Q, r+h.R
+	}
+	n := int64(len(hexes))
+	centroid := hex.Hex{Q: q / n, R: r / n}
+	best := hexes[0]
+	for _, h := range hexes {
+		if hex.HexDistance(h, centroid) < hex.HexDistance(best, centroid) {
+			best = h
+		}
+	}
+	return best
+}
+
+// digCorridors connects the rooms along a minimum spanning tree of their
+// centers, then adds extra corridors from random rooms to their nearest
+// room not already joined to them.
+func (d *Dungeon) digCorridors(rng *rand.Rand, extra int) {
+	pathfinder := hex.NewBoundedPathfinder(d.Tiles.Hexes())
+	joined := map[[2]int]bool{}
+	join := func(a, b int) {
+		joined[[2]int{a, b}], joined[[2]int{b, a}] = true, true
+		d.dig(pathfinder, a, b)
+	}
+
+	// Prim's algorithm, breaking ties by room order.
+	inTree := make([]bool, len(d.Rooms))
+	inTree[0] = true
+	for range len(d.Rooms) - 1 {
+		bestFrom, bestTo := -1, -1
+		var bestDistance int64
+		for a := range d.Rooms {
+			for b := range d.Rooms {
+				if !inTree[a] || inTree[b] {
+					continue
+				}
+				distance := hex.HexDistance(d.Rooms[a].Center, d.Rooms[b].Center)
+				if bestTo < 0 || distance < bestDistance {
+					bestFrom, bestTo, bestDistance = a, b, distance
+				}
+			}
+		}
+		inTree[bestTo] = true
+		join(bestFrom, bestTo)
+	}
+
+	for range extra {
+		a := rng.IntN(len(d.Rooms))
+		nearest := -1
+		for b := range d.Rooms {
+			if b == a || joined[[2]int{a, b}] {
+				continue
+			}
+			if nearest < 0 || hex.HexDistance(d.Rooms[a].Center, d.Rooms[b].Center) < hex.HexDistance(d.Rooms[a].Center, d.Rooms[nearest].Center) {
+				nearest = b
+			}
+		}
+		if nearest >= 0 {
+			join(a, nearest)
+		}
+	}
+}
+
+// Costs of the field corridors are dug over. Rock costs more than floor, so
+// new corridors reuse old ones where that is not much of a detour.
+const (
+	floorDigCost = 1
+	rockDigCost  = 2
+)
+
+// dig searches a cost field for a passage from the center of room a to the
+// center of room b that keeps clear of every other room, then turns the rock
+// along it into corridor, with a door wherever it leaves or enters a room.
+func (d *Dungeon) dig(pathfinder *hex.Pathfinder, a, b int) {
+	ends := func(h hex.Hex) bool {
+		id, ok := d.roomAt[h]
+		return ok && (id == a || id == b)
+	}
+	walkable := func(h hex.Hex) bool {
+		if ends(h) {
+			return true
+		}
+		if _, ok := d.roomAt[h]; ok || !d.interior(h) {
+			return false
+		}
+		for _, n := range hex.GetNeighbors(h) {
+			if _, ok := d.roomAt[n]; ok && !ends(n) {
+				return false
+			}
+		}
+		return true
+	}
+	cost := d.Tiles.Cost(func(t DungeonTile) int {
+		if t == Rock {
+			return rockDigCost
+		}
+		return floorDigCost
+	})
+
+	path, _ := pathfinder.FindWeightedPath(d.Rooms[a].Center, d.Rooms[b].Center, walkable, cost, floorDigCost)
+	if path == nil {
+		return
+	}
+
+	corridor := Corridor{Rooms: [2]int{a, b}}
+	for i, h := range path {
+		if !ends(h) {
+			corridor.Hexes = append(corridor.Hexes, h)
+			d.Tiles.Set(h, CorridorFloor)
+		}
+		if i > 0 && ends(path[i-1]) != ends(h) {
+			door, _ := hex.EdgeBetween(path[i-1], h)
+			corridor.Doors = append(corridor.Doors, door)
+			d.doors[door] = true
+		}
+	}
+	d.Corridors = append(d.Corridors, corridor)
+}
+
+// linkRooms fills in each room's Links by walking the corridors from its
+// doors. Corridors that cross join up, so this finds every room a party can
+// reach, not just the ones a corridor was dug to.
+func (d *Dungeon) linkRooms() {
+	for i := range d.Rooms {
+		room := &d.Rooms[i]
+		linked := map[int]bool{}
+		visited := map[hex.Hex]bool{}
+		fringe := []hex.Hex{}
+		for door := range d.doors {
+			x, y := door.Hexes()
+			if id, ok := d.roomAt[x]; ok && id == room.ID {
+				fringe = append(fringe, y)
+			} else if id, ok := d.roomAt[y]; ok && id == room.ID {
+				fringe = append(fringe, x)
+			}
+		}
+
+		for len(fringe) > 0 {
+			h := fringe[len(fringe)-1]
+			fringe = fringe[:len(fringe)-1]
+			if visited[h] {
+				continue
+			}
+			visited[h] = true
+			for _, n := range hex.GetNeighbors(h) {
+				if t, _ := d.Tiles.Get(n); t == CorridorFloor {
+					fringe = append(fringe, n)
+				} else if id, ok := d.roomAt[n]; ok && id != room.ID && !d.IsEdgeBlocked(mustEdge(h, n)) {
+					linked[id] = true
+				}
+			}
+		}
+		room.Links = slices.Sorted(maps.Keys(linked))
+	}
+}
+
+func mustEdge(a, b hex.Hex) hex.Edge {
+	e, _ := hex.EdgeBetween(a, b)
+	return e
+}
+
+// connected reports whether every room can be reached from the first.
+func (d *Dungeon) connected() bool {
+	return len(d.hopsFrom(0)) == len(d.Rooms)
+}
+
+// hopsFrom returns how many corridors separate each reachable room from
+// room start.
+func (d *Dungeon) hopsFrom(start int) map[int]int {
+	hops := map[int]int{start: 0}
+	queue := []int{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range d.Rooms[id].Links {
+			if _, seen := hops[next]; !seen {
+				hops[next] = hops[id] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return hops
+}
+
+// tagRooms makes the first room the start and the room the most corridors
+// away from it the boss's lair. Treasure goes in the dead ends furthest
+// from the start; every other room is an encounter.
+func (d *Dungeon) tagRooms(treasure int) {
+	hops := d.hopsFrom(0)
+	byDistance := make([]int, len(d.Rooms))
+	for i := range byDistance {
+		byDistance[i] = i
+	}
+	slices.SortStableFunc(byDistance, func(a, b int) int {
+		return hops[b] - hops[a]
+	})
+
+	d.Rooms[0].Kind = StartRoom
+	boss := byDistance[0]
+	d.Rooms[boss].Kind = BossRoom
+	for _, id := range byDistance {
+		if treasure == 0 {
+			break
+		}
+		if id != 0 && id != boss && len(d.Rooms[id].Links) == 1 {
+			d.Rooms[id].Kind = TreasureRoom
+			treasure--
+		}
+	}
+}
+
+// RoomAt returns the room covering h.
+func (d *Dungeon) RoomAt(h hex.Hex) (*Room, bool) {
+	id, ok := d.roomAt[h]
+	if !ok {
+		return nil, false
+	}
+	return &d.Rooms[id], true
+}
+
+// RoomsOfKind returns the IDs of every room of the given kind.
+func (d *Dungeon) RoomsOfKind(kind RoomKind) []int {
+	ids := []int{}
+	for _, room := range d.Rooms {
+		if room.Kind == kind {
+			ids = append(ids, room.ID)
+		}
+	}
+	return ids
+}
+
+// Walkable reports whether h is room or corridor floor. It has the
+// signature of an isWalkable callback.
+func (d *Dungeon) Walkable(h hex.Hex) bool {
+	t, ok := d.Tiles.Get(h)
+	return ok && t != Rock
+}
+
+// IsDoor reports whether e has a door on it.
+func (d *Dungeon) IsDoor(e hex.Edge) bool {
+	return d.doors[e]
+}
+
+// Doors returns every door, sorted by position.
+func (d *Dungeon) Doors() []hex.Edge {
+	doors := slices.Collect(maps.Keys(d.doors))
+	slices.SortFunc(doors, func(a, b hex.Edge) int {
+		if c := compareHexes(a.Hex, b.Hex); c != 0 {
+			return c
+		}
+		return int(a.Side - b.Side)
+	})
+	return doors
+}
+
+// IsEdgeBlocked reports whether a wall stands on e: every edge between a
+// room and the rest of the dungeon is walled, except where there is a door.
+// It has the signature of an isEdgeBlocked callback, so with Walkable it
+// plugs straight into hex.FindPathWithEdges.
+func (d *Dungeon) IsEdgeBlocked(e hex.Edge) bool {
+	if d.doors[e] {
+		return false
+	}
+	a, b := e.Hexes()
+	roomA, inA := d.roomAt[a]
+	roomB, inB := d.roomAt[b]
+	return inA != inB || inA && roomA != roomB
+}
+
+func compareHexes(a, b hex.Hex) int {
+	if c := cmp.Compare(a.Q, b.Q); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.R, b.R)
+}
@@ -0,0 +1,153 @@
+package mapgen
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+// TestGenerateDungeon verifies every room can be walked to from the start
+// through doors, and the rooms are tagged and linked consistently
+func TestGenerateDungeon(t *testing.T) {
+	step := hex.HexCost(func(hex.Hex) int { return 1 })
+	for seed := uint64(0); seed < 20; seed++ {
+		config := DefaultDungeonConfig()
+		config.Seed = seed
+		d, err := GenerateDungeon(config)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if len(d.Rooms) < config.Rooms/2 {
+			t.Errorf("seed %d: only %d of %d rooms placed", seed, len(d.Rooms), config.Rooms)
+		}
+
+		start := d.Rooms[d.RoomsOfKind(StartRoom)[0]]
+		if bosses := d.RoomsOfKind(BossRoom); len(bosses) != 1 || bosses[0] == start.ID {
+			t.Errorf("seed %d: boss rooms %v", seed, bosses)
+		}
+		if treasure := d.RoomsOfKind(TreasureRoom); len(treasure) > config.TreasureRooms {
+			t.Errorf("seed %d: %d treasure rooms", seed, len(treasure))
+		}
+
+		for _, room := range d.Rooms {
+			for _, h := range room.Hexes {
+				if tile, _ := d.Tiles.Get(h); tile != RoomFloor {
+					t.Fatalf("seed %d: room %d hex %v is %v", seed, room.ID, h, tile)
+				}
+			}
+			for _, other := range room.Links {
+				if !slices.Contains(d.Rooms[other].Links, room.ID) {
+					t.Errorf("seed %d: room %d links to %d but not back", seed, room.ID, other)
+				}
+			}
+			if path, _ := hex.FindPathWithEdges(start.Center, room.Center, d.Walkable, d.IsEdgeBlocked, step, 1); path == nil {
+				t.Errorf("seed %d: room %d cannot be reached from the start", seed, room.ID)
+			}
+		}
+
+		for _, door := range d.Doors() {
+			a, b := door.Hexes()
+			ta, _ := d.Tiles.Get(a)
+			tb, _ := d.Tiles.Get(b)
+			if min(ta, tb) != RoomFloor || max(ta, tb) != CorridorFloor {
+				t.Errorf("seed %d: door %v between %v and %v", seed, door, ta, tb)
+			}
+		}
+	}
+}
+
+// TestGenerateDungeonRejectsEmptyMap verifies a map with no area is an
+// error rather than a panic
+func TestGenerateDungeonRejectsEmptyMap(t *testing.T) {
+	for _, size := range [][2]int64{{0, 40}, {60, 0}, {-1, 40}} {
+		config := DefaultDungeonConfig()
+		config.Width, config.Height = size[0], size[1]
+		if d, err := GenerateDungeon(config); err == nil {
+			t.Errorf("%dx%d dungeon generated with %d rooms", size[0], size[1], len(d.Rooms))
+		}
+	}
+}
+
+// TestDungeonWalls verifies corridors only lead into rooms through doors
+func TestDungeonWalls(t *testing.T) {
+	d, err := GenerateDungeon(DefaultDungeonConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, room := range d.Rooms {
+		openings := 0
+		for _, h := range room.Hexes {
+			for _, e := range hex.Edges(h) {
+				a, b := e.Hexes()
+				outside := a
+				if outside == h {
+					outside = b
+				}
+				if _, inRoom := d.RoomAt(outside); !inRoom && d.Walkable(outside) && !d.IsEdgeBlocked(e) {
+					openings++
+					if !d.IsDoor(e) {
+						t.Errorf("room %d opens onto %v without a door", room.ID, outside)
+					}
+				}
+			}
+		}
+		if len(room.Links) > 0 && openings == 0 {
+			t.Errorf("room %d has links %v but no doors", room.ID, room.Links)
+		}
+	}
+}
+
+// TestGenerateDungeonIsDeterministic verifies a seed always gives the same
+// dungeon
+func TestGenerateDungeonIsDeterministic(t *testing.T) {
+	config := DefaultDungeonConfig()
+	config.Seed = 7
+	a, _ := GenerateDungeon(config)
+	b, _ := GenerateDungeon(config)
+	for h, tile := range a.Tiles.All() {
+		if other, _ := b.Tiles.Get(h); other != tile {
+			t.Fatalf("%v: %v, then %v", h, tile, other)
+		}
+	}
+	for i := range a.Rooms {
+		if a.Rooms[i].Kind != b.Rooms[i].Kind || !slices.Equal(a.Rooms[i].Links, b.Rooms[i].Links) {
+			t.Fatalf("room %d: %+v, then %+v", i, a.Rooms[i], b.Rooms[i])
+		}
+	}
+	if !slices.Equal(a.Doors(), b.Doors()) {
+		t.Error("doors differ")
+	}
+
+	crowded := DefaultDungeonConfig()
+	crowded.Width, crowded.Height = 6, 6
+	if _, err := GenerateDungeon(crowded); err == nil {
+		t.Error("a map too small for two rooms was accepted")
+	}
+}
+
+// TestDungeonValidate verifies a dungeon is rejected once the doors on the
+// way to the boss are bricked up
+func TestDungeonValidate(t *testing.T) {
+	d, err := GenerateDungeon(DefaultDungeonConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Validate(); err != nil {
+		t.Fatalf("generated dungeon rejected: %v", err)
+	}
+
+	boss := d.Rooms[d.RoomsOfKind(BossRoom)[0]]
+	for door := range d.doors {
+		a, b := door.Hexes()
+		if room, ok := d.RoomAt(a); ok && room.ID == boss.ID {
+			delete(d.doors, door)
+		} else if room, ok := d.RoomAt(b); ok && room.ID == boss.ID {
+			delete(d.doors, door)
+		}
+	}
+	if err := d.Validate(); !errors.Is(err, ErrBossUnreachable) {
+		t.Errorf("boss room without doors: %v", err)
+	}
+}
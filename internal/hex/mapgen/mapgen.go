@@ -0,0 +1,297 @@
+// Package mapgen fills hex maps with procedural terrain. Elevation and
+// moisture come from seeded noise, biomes are picked from the two, caves are
+// grown inside mountains with a cellular automaton, and spawn points are
+// joined up so every party can reach every other. The same seed and map
+// shape always give the same map.
+package mapgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+// Terrain is the biome of a single hex.
+type Terrain int
+
+const (
+	DeepWater Terrain = iota
+	ShallowWater
+	Beach
+	Desert
+	Grassland
+	Forest
+	Swamp
+	Hills
+	Mountain
+	// Cave is open floor carved out of a mountain.
+	Cave
+)
+
+func (t Terrain) String() string {
+	switch t {
+	case DeepWater:
+		return "deep water"
+	case ShallowWater:
+		return "shallow water"
+	case Beach:
+		return "beach"
+	case Desert:
+		return "desert"
+	case Grassland:
+		return "grassland"
+	case Forest:
+		return "forest"
+	case Swamp:
+		return "swamp"
+	case Hills:
+		return "hills"
+	case Mountain:
+		return "mountain"
+	default:
+		return "cave"
+	}
+}
+
+// Walkable reports whether units can stand on the terrain. Deep water and
+// solid mountain are impassable.
+func (t Terrain) Walkable() bool {
+	return t != DeepWater && t != Mountain
+}
+
+// Cost returns the movement cost of entering the terrain, for use with
+// Map.Cost. Impassable terrain returns 0.
+func (t Terrain) Cost() int {
+	switch t {
+	case DeepWater, Mountain:
+		return 0
+	case ShallowWater, Swamp:
+		return 3
+	case Forest, Hills, Desert:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Cell is what the generator stores for each hex. Elevation and Moisture
+// are in [0, 1).
+type Cell struct {
+	Terrain   Terrain
+	Elevation float64
+	Moisture  float64
+}
+
+// Walkable is an isWalkable predicate over cells, for Map.Walkable.
+func Walkable(c Cell) bool {
+	return c.Terrain.Walkable()
+}
+
+// Cost is a per-cell movement cost, for Map.Cost.
+func Cost(c Cell) int {
+	return c.Terrain.Cost()
+}
+
+// Config controls the generator. Start from DefaultConfig and set a Seed.
+type Config struct {
+	Seed uint64
+	// Scale is roughly how many hexes across a continent or lake is.
+	Scale float64
+	// Octaves is how many layers of finer noise add detail. There is always
+	// at least one.
+	Octaves int
+
+	// Elevation thresholds, in [0, 1). Hexes below SeaLevel are water,
+	// those above HillLevel hills and those above MountainLevel mountain.
+	SeaLevel      float64
+	HillLevel     float64
+	MountainLevel float64
+
+	// CaveFill is the share of mountain hexes that start out solid before
+	// the cave automaton runs for CaveSteps rounds. Lower values give
+	// bigger caves; a CaveSteps of 0 leaves mountains solid.
+	CaveFill  float64
+	CaveSteps int
+
+	// Spawns are the centers of the zones parties start in. Every hex
+	// within SpawnRadius of one is made walkable and, where the terrain cuts
+	// a spawn off from the first, a passage is carved between them.
+	Spawns      []hex.Hex
+	SpawnRadius int64
+}
+
+// DefaultConfig returns settings that give a mix of coast, open ground and
+// mountains on a map a few dozen hexes across.
+func DefaultConfig() Config {
+	return Config{
+		Scale:         12,
+		Octaves:       4,
+		SeaLevel:      0.38,
+		HillLevel:     0.58,
+		MountainLevel: 0.64,
+		CaveFill:      0.45,
+		CaveSteps:     4,
+		SpawnRadius:   1,
+	}
+}
+
+// Classify picks the terrain for a hex from its elevation and moisture.
+// Elevation decides water, coast, hills and mountains; moisture decides
+// the lowland biome between them.
+func (c Config) Classify(elevation, moisture float64) Terrain {
+	switch {
+	case elevation < c.SeaLevel-0.08:
+		return DeepWater
+	case elevation < c.SeaLevel:
+		return ShallowWater
+	case elevation < c.SeaLevel+0.02:
+		return Beach
+	case elevation >= c.MountainLevel:
+		return Mountain
+	case elevation >= c.HillLevel:
+		return Hills
+	case moisture < 0.35:
+		return Desert
+	case moisture < 0.55:
+		return Grassland
+	case moisture < 0.68:
+		return Forest
+	default:
+		return Swamp
+	}
+}
+
+// Generate fills every hex of m. It fails only when a spawn lies outside
+// the map.
+func Generate(m *hex.Map[Cell], config Config) error {
+	for _, s := range config.Spawns {
+		if !m.Contains(s) {
+			return fmt.Errorf("spawn %v is outside the map", s)
+		}
+	}
+
+	elevation := noise{seed: mix(config.Seed)}
+	moisture := noise{seed: mix(config.Seed + 1)}
+	scale := math.Max(config.Scale, 1)
+	octaves := max(config.Octaves, 1)
+	m.Fill(func(h hex.Hex) Cell {
+		// Sample at the hex centers on a plane where neighbors are one unit
+		// apart, so features are round whatever the layout.
+		x := (float64(h.Q) + float64(h.R)/2) / scale
+		y := float64(h.R) * math.Sqrt(3) / 2 / scale
+		cell := Cell{
+			Elevation: elevation.fractal(x, y, octaves),
+			Moisture:  moisture.fractal(x+1000, y+1000, octaves),
+		}
+		cell.Terrain = config.Classify(cell.Elevation, cell.Moisture)
+		return cell
+	})
+
+	rng := rand.New(rand.NewPCG(config.Seed, 0x6d617067656e))
+	carveCaves(m, rng, config.CaveFill, config.CaveSteps)
+	connectSpawns(m, config.Spawns, config.SpawnRadius)
+	return nil
+}
+
+// carveCaves runs a cellular automaton over the mountain hexes. Each starts
+// solid with probability fill; then, every step, a hex with four or more
+// solid neighbors turns solid and one with two or fewer opens up. Hexes
+// outside the mountains count as solid, so caves keep to the mountains'
+// interiors and only now and then open a mouth onto the slopes.
+func carveCaves(m *hex.Map[Cell], rng *rand.Rand, fill float64, steps int) {
+	if steps <= 0 {
+		return
+	}
+
+	solid := map[hex.Hex]bool{}
+	mountains := []hex.Hex{}
+	for h, cell := range m.All() {
+		if cell.Terrain == Mountain {
+			mountains = append(mountains, h)
+			solid[h] = rng.Float64() < fill
+		}
+	}
+
+	for range steps {
+		next := make(map[hex.Hex]bool, len(solid))
+		for _, h := range mountains {
+			walls := 0
+			for _, n := range hex.GetNeighbors(h) {
+				if isSolid, ok := solid[n]; !ok || isSolid {
+					walls++
+				}
+			}
+			switch {
+			case walls >= 4:
+				next[h] = true
+			case walls <= 2:
+				next[h] = false
+			default:
+				next[h] = solid[h]
+			}
+		}
+		solid = next
+	}
+
+	for _, h := range mountains {
+		if !solid[h] {
+			cell, _ := m.Get(h)
+			cell.Terrain = Cave
+			m.Set(h, cell)
+		}
+	}
+}
+
+// carveCost is what the passage search pays to cut through impassable
+// terrain, so it only does so where going around is far longer.
+const carveCost = 8
+
+// connectSpawns clears the zone around every spawn and carves a passage
+// from the first spawn to any the terrain cuts off. Mountains are tunneled
+// into cave and deep water made shallow.
+func connectSpawns(m *hex.Map[Cell], spawns []hex.Hex, radius int64) {
+	for _, s := range spawns {
+		for _, h := range hex.Range(s, radius) {
+			if m.Contains(h) {
+				open(m, h)
+			}
+		}
+	}
+	if len(spawns) < 2 {
+		return
+	}
+
+	walkable := m.Walkable(Walkable)
+	carve := m.Cost(func(c Cell) int {
+		if !c.Terrain.Walkable() {
+			return carveCost
+		}
+		return c.Terrain.Cost()
+	})
+	pathfinder := hex.NewBoundedPathfinder(m.Hexes())
+	for _, s := range spawns[1:] {
+		if pathfinder.FindPath(spawns[0], s, walkable) != nil {
+			continue
+		}
+		passage, _ := pathfinder.FindWeightedPath(spawns[0], s, m.Contains, carve, 1)
+		for _, h := range passage {
+			open(m, h)
+		}
+	}
+}
+
+// open turns impassable terrain at h into the nearest walkable kind.
+func open(m *hex.Map[Cell], h hex.Hex) {
+	cell, _ := m.Get(h)
+	switch cell.Terrain {
+	case DeepWater:
+		cell.Terrain = ShallowWater
+	case Mountain:
+		cell.Terrain = Cave
+	default:
+		return
+	}
+	m.Set(h, cell)
+}
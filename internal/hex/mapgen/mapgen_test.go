@@ -0,0 +1,179 @@
+package mapgen
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+func generate(t *testing.T, config Config) *hex.Map[Cell] {
+	t.Helper()
+	m := hex.NewRectangularMap[Cell](48, 32)
+	if err := Generate(m, config); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// TestGenerateIsDeterministic verifies a seed always gives the same map and
+// different seeds give different maps
+func TestGenerateIsDeterministic(t *testing.T) {
+	config := DefaultConfig()
+	config.Seed = 42
+	a, b := generate(t, config), generate(t, config)
+	for h, cell := range a.All() {
+		if other, _ := b.Get(h); other != cell {
+			t.Fatalf("%v: %+v, then %+v", h, cell, other)
+		}
+	}
+
+	config.Seed = 43
+	c := generate(t, config)
+	differ := 0
+	for h, cell := range a.All() {
+		if other, _ := c.Get(h); other.Terrain != cell.Terrain {
+			differ++
+		}
+	}
+	if differ < a.Len()/4 {
+		t.Errorf("only %d of %d hexes differ between seeds", differ, a.Len())
+	}
+}
+
+// TestGenerateWithoutOctaves verifies a zero Octaves still gives real
+// elevation and moisture instead of NaN
+func TestGenerateWithoutOctaves(t *testing.T) {
+	config := DefaultConfig()
+	config.Octaves = 0
+	m := generate(t, config)
+	terrains := map[Terrain]bool{}
+	for h, cell := range m.All() {
+		if math.IsNaN(cell.Elevation) || math.IsNaN(cell.Moisture) {
+			t.Fatalf("%v: %+v", h, cell)
+		}
+		terrains[cell.Terrain] = true
+	}
+	if len(terrains) < 3 {
+		t.Errorf("only %d terrains on the map", len(terrains))
+	}
+}
+
+// TestClassify verifies elevation picks water, coast and highlands, and
+// moisture picks the lowland biome
+func TestClassify(t *testing.T) {
+	config := DefaultConfig()
+	tests := []struct {
+		elevation, moisture float64
+		want                Terrain
+	}{
+		{0.1, 0.9, DeepWater},
+		{config.SeaLevel - 0.01, 0.1, ShallowWater},
+		{config.SeaLevel + 0.01, 0.5, Beach},
+		{0.5, 0.1, Desert},
+		{0.5, 0.45, Grassland},
+		{0.5, 0.6, Forest},
+		{0.5, 0.9, Swamp},
+		{config.HillLevel, 0.9, Hills},
+		{config.MountainLevel, 0.1, Mountain},
+	}
+	for _, tt := range tests {
+		if got := config.Classify(tt.elevation, tt.moisture); got != tt.want {
+			t.Errorf("Classify(%v, %v) = %v, want %v", tt.elevation, tt.moisture, got, tt.want)
+		}
+	}
+}
+
+// TestCavesStayInMountains verifies the automaton carves caves only out of
+// mountains, and mostly from their interiors
+func TestCavesStayInMountains(t *testing.T) {
+	caves, mouths := 0, 0
+	for seed := uint64(0); seed < 5; seed++ {
+		config := DefaultConfig()
+		config.Seed = seed
+		m := generate(t, config)
+		for h, cell := range m.All() {
+			if cell.Terrain != Cave {
+				continue
+			}
+			caves++
+			if cell.Elevation < config.MountainLevel {
+				t.Fatalf("seed %d: cave at %v below the mountains", seed, h)
+			}
+			for _, n := range m.Neighbors(h) {
+				if other, _ := m.Get(n); other.Terrain != Cave && other.Terrain != Mountain {
+					mouths++
+					break
+				}
+			}
+		}
+	}
+	if caves == 0 || mouths > caves/4 {
+		t.Errorf("%d cave hexes, %d of them open onto the slopes", caves, mouths)
+	}
+
+	config := DefaultConfig()
+	config.CaveSteps = 0
+	for _, cell := range generate(t, config).All() {
+		if cell.Terrain == Cave {
+			t.Fatal("caves carved with no automaton steps")
+		}
+	}
+}
+
+// TestSpawnsAreConnected verifies every spawn zone is walkable and can
+// reach the others, whatever the terrain between them
+func TestSpawnsAreConnected(t *testing.T) {
+	m := hex.NewRectangularMap[Cell](48, 32)
+	hexes := m.Hexes()
+	spawns := []hex.Hex{hexes[0], hexes[len(hexes)-1], hexes[len(hexes)/2]}
+
+	for seed := uint64(0); seed < 20; seed++ {
+		config := DefaultConfig()
+		config.Seed = seed
+		config.Spawns = spawns
+		// Drown most of the map so spawns are often cut off
+		config.SeaLevel = 0.5
+		if err := Generate(m, config); err != nil {
+			t.Fatal(err)
+		}
+
+		walkable := m.Walkable(Walkable)
+		for _, s := range spawns {
+			for _, h := range hex.Range(s, config.SpawnRadius) {
+				if m.Contains(h) && !walkable(h) {
+					t.Errorf("seed %d: %v in the zone around %v is blocked", seed, h, s)
+				}
+			}
+			if hex.FindPath(spawns[0], s, walkable) == nil {
+				t.Errorf("seed %d: %v cannot reach %v", seed, spawns[0], s)
+			}
+		}
+	}
+
+	config := DefaultConfig()
+	config.Spawns = []hex.Hex{{Q: -1, R: 0}}
+	if err := Generate(m, config); err == nil {
+		t.Error("spawn outside the map was accepted")
+	}
+}
+
+// TestValidateMap verifies a map is rejected once water cuts the boss off
+func TestValidateMap(t *testing.T) {
+	m := hex.NewRectangularMap[Cell](20, 10)
+	m.Fill(func(hex.Hex) Cell { return Cell{Terrain: Grassland} })
+	party, boss := hex.Hex{Q: 1, R: 2}, hex.Hex{Q: 18, R: -5}
+	if err := ValidateMap(m, party, boss); err != nil {
+		t.Fatalf("open map rejected: %v", err)
+	}
+
+	for h := range m.All() {
+		if h.Q == 10 {
+			m.Set(h, Cell{Terrain: DeepWater})
+		}
+	}
+	if err := ValidateMap(m, party, boss); !errors.Is(err, ErrBossUnreachable) {
+		t.Errorf("map split by water: %v", err)
+	}
+}
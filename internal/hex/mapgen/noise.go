@@ -0,0 +1,57 @@
+package mapgen
+
+import "math"
+
+// noise is seeded value noise: random values on a unit lattice, blended
+// smoothly in between and summed over several octaves for detail.
+type noise struct {
+	seed uint64
+}
+
+// lattice returns the random value in [0, 1) at a lattice point.
+func (n noise) lattice(x, y int64) float64 {
+	h := mix(n.seed ^ mix(uint64(x)^mix(uint64(y))))
+	return float64(h>>11) / (1 << 53)
+}
+
+// mix is the splitmix64 finalizer, which scrambles every input bit into
+// every output bit.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// value returns smoothly interpolated noise in [0, 1) at (x, y).
+func (n noise) value(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := smoothstep(x-x0), smoothstep(y-y0)
+	ix, iy := int64(x0), int64(y0)
+
+	top := lerp(n.lattice(ix, iy), n.lattice(ix+1, iy), tx)
+	bottom := lerp(n.lattice(ix, iy+1), n.lattice(ix+1, iy+1), tx)
+	return lerp(top, bottom, ty)
+}
+
+// fractal sums octaves of noise, each twice as fine and half as strong as
+// the one before, and scales the result back into [0, 1).
+func (n noise) fractal(x, y float64, octaves int) float64 {
+	total, amplitude, weight := 0.0, 1.0, 0.0
+	for i := range octaves {
+		octave := noise{seed: mix(n.seed + uint64(i))}
+		total += amplitude * octave.value(x, y)
+		weight += amplitude
+		x, y = x*2, y*2
+		amplitude /= 2
+	}
+	return total / weight
+}
+
+func smoothstep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
@@ -0,0 +1,41 @@
+package mapgen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alde/hexy-and-i-know-it/internal/hex"
+)
+
+// ErrBossUnreachable is returned when the party could not walk from where
+// it spawns to the boss.
+var ErrBossUnreachable = errors.New("party spawn cannot reach the boss")
+
+// ValidateReachable rejects a map on which the party, spawning on party,
+// cannot walk to the boss on boss. isWalkable must bound the map, as
+// Map.Walkable does; isEdgeBlocked may be nil on maps without walls on hex
+// borders.
+func ValidateReachable(party, boss hex.Hex, isWalkable func(hex.Hex) bool, isEdgeBlocked func(hex.Edge) bool) error {
+	if isEdgeBlocked == nil {
+		isEdgeBlocked = func(hex.Edge) bool { return false }
+	}
+	step := hex.HexCost(func(hex.Hex) int { return 1 })
+	if path, _ := hex.FindPathWithEdges(party, boss, isWalkable, isEdgeBlocked, step, 1); path == nil {
+		return fmt.Errorf("%w: no path from %v to %v", ErrBossUnreachable, party, boss)
+	}
+	return nil
+}
+
+// ValidateMap is ValidateReachable for a map filled by Generate.
+func ValidateMap(m *hex.Map[Cell], party, boss hex.Hex) error {
+	return ValidateReachable(party, boss, m.Walkable(Walkable), nil)
+}
+
+// Validate rejects a dungeon whose start room cannot reach its boss room.
+func (d *Dungeon) Validate() error {
+	start, boss := d.RoomsOfKind(StartRoom), d.RoomsOfKind(BossRoom)
+	if len(start) == 0 || len(boss) == 0 {
+		return fmt.Errorf("%w: dungeon has no start or boss room", ErrBossUnreachable)
+	}
+	return ValidateReachable(d.Rooms[start[0]].Center, d.Rooms[boss[0]].Center, d.Walkable, d.IsEdgeBlocked)
+}
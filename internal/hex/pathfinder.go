@@ -0,0 +1,341 @@
+package hex
+
+import (
+	"context"
+	"slices"
+)
+
+// cancelCheckInterval is how many hexes a search expands between checks of
+// its context, so cancellation stays cheap.
+const cancelCheckInterval = 256
+
+// Pathfinder runs the same A* search as FindWeightedPath, but keeps its
+// buffers between searches so repeated calls, such as re-pathing to the
+// hovered hex every frame, stop allocating once the buffers have grown to
+// fit the map. A Pathfinder is not safe for concurrent use.
+//
+// A bounded Pathfinder, from NewBoundedPathfinder, indexes hexes with plain
+// slices instead of maps and treats everything outside its bounds as
+// unwalkable.
+type Pathfinder struct {
+	// StraightLines breaks ties between equally cheap paths in favor of the
+	// one that hugs the straight line from start to goal, so paths across
+	// open ground look less like staircases. It never changes the cost.
+	StraightLines bool
+
+	dense         bool
+	minQ, minR    int64
+	width, height int64
+	index         map[Hex]int32
+
+	// Per-slot search state. seen and closed hold the generation they were
+	// last set in, so nothing needs clearing between searches.
+	hexes      []Hex
+	g          []int
+	parent     []int32
+	seen       []uint32
+	closed     []uint32
+	generation uint32
+
+	open []openNode
+	path []Hex
+}
+
+type openNode struct {
+	f    int
+	tie  int64
+	h    int
+	slot int32
+}
+
+// less orders by estimated total cost, then by the tie-breaker, then
+// prefers nodes closer to the goal.
+func (a openNode) less(b openNode) bool {
+	if a.f != b.f {
+		return a.f < b.f
+	}
+	if a.tie != b.tie {
+		return a.tie < b.tie
+	}
+	return a.h < b.h
+}
+
+// NewPathfinder returns a Pathfinder for unbounded maps.
+func NewPathfinder() *Pathfinder {
+	return &Pathfinder{index: map[Hex]int32{}}
+}
+
+// NewBoundedPathfinder returns a Pathfinder limited to the parallelogram
+// spanned by hexes. Its buffers are sized up front, so even the first
+// search only allocates the returned path.
+func NewBoundedPathfinder(hexes []Hex) *Pathfinder {
+	if len(hexes) == 0 {
+		return NewPathfinder()
+	}
+
+	p := &Pathfinder{dense: true, minQ: hexes[0].Q, minR: hexes[0].R}
+	maxQ, maxR := p.minQ, p.minR
+	for _, h := range hexes {
+		p.minQ, maxQ = min(p.minQ, h.Q), max(maxQ, h.Q)
+		p.minR, maxR = min(p.minR, h.R), max(maxR, h.R)
+	}
+	p.width, p.height = maxQ-p.minQ+1, maxR-p.minR+1
+
+	size := p.width * p.height
+	p.hexes = make([]Hex, size)
+	for i := range p.hexes {
+		p.hexes[i] = Hex{Q: p.minQ + int64(i)/p.height, R: p.minR + int64(i)%p.height}
+	}
+	p.g = make([]int, size)
+	p.parent = make([]int32, size)
+	p.seen = make([]uint32, size)
+	p.closed = make([]uint32, size)
+	p.open = make([]openNode, 0, 6*max(p.width, p.height))
+	return p
+}
+
+// FindPath is FindPath using the Pathfinder's buffers. The returned path is
+// reused by the next search; clone it to keep it.
+func (p *Pathfinder) FindPath(start, goal Hex, isWalkable func(Hex) bool) []Hex {
+	path, _ := p.FindWeightedPath(start, goal, isWalkable, uniformCost, 1)
+	return path
+}
+
+// FindWeightedPath is FindWeightedPath using the Pathfinder's buffers. The
+// returned path is reused by the next search; clone it to keep it.
+func (p *Pathfinder) FindWeightedPath(start, goal Hex, isWalkable func(Hex) bool, cost CostFunc, minCost int) ([]Hex, int) {
+	result := p.search(context.Background(), start, goal, func(_, to Hex) bool { return isWalkable(to) }, cost, minCost, SearchLimits{})
+	return result.Path, result.Cost
+}
+
+// FindPathWithEdges is FindPathWithEdges using the Pathfinder's buffers. The
+// returned path is reused by the next search; clone it to keep it.
+func (p *Pathfinder) FindPathWithEdges(start, goal Hex, isWalkable func(Hex) bool, isEdgeBlocked func(Edge) bool, cost CostFunc, minCost int) ([]Hex, int) {
+	result := p.search(context.Background(), start, goal, edgeStep(isWalkable, isEdgeBlocked), cost, minCost, SearchLimits{})
+	return result.Path, result.Cost
+}
+
+// Search is Search using the Pathfinder's buffers. The returned path is
+// reused by the next search; clone it to keep it.
+func (p *Pathfinder) Search(ctx context.Context, start, goal Hex, isWalkable func(Hex) bool, cost CostFunc, minCost int, limits SearchLimits) PathResult {
+	return p.checkedSearch(ctx, start, goal, isWalkable, func(_, to Hex) bool { return isWalkable(to) }, cost, minCost, limits)
+}
+
+// SearchWithEdges is SearchWithEdges using the Pathfinder's buffers.
+func (p *Pathfinder) SearchWithEdges(ctx context.Context, start, goal Hex, isWalkable func(Hex) bool, isEdgeBlocked func(Edge) bool, cost CostFunc, minCost int, limits SearchLimits) PathResult {
+	return p.checkedSearch(ctx, start, goal, isWalkable, edgeStep(isWalkable, isEdgeBlocked), cost, minCost, limits)
+}
+
+// checkedSearch refuses to start from a blocked hex, and only searches
+// toward a blocked goal when the closest reachable hex was asked for.
+func (p *Pathfinder) checkedSearch(ctx context.Context, start, goal Hex, isWalkable func(Hex) bool, canStep func(from, to Hex) bool, cost CostFunc, minCost int, limits SearchLimits) PathResult {
+	if !isWalkable(start) {
+		return PathResult{Status: PathStartBlocked}
+	}
+	goalBlocked := !isWalkable(goal)
+	if goalBlocked && !limits.Closest {
+		return PathResult{Status: PathGoalBlocked}
+	}
+	result := p.search(ctx, start, goal, canStep, cost, minCost, limits)
+	if goalBlocked && result.Status != PathCancelled {
+		result.Status = PathGoalBlocked
+	}
+	return result
+}
+
+func edgeStep(isWalkable func(Hex) bool, isEdgeBlocked func(Edge) bool) func(from, to Hex) bool {
+	return func(from, to Hex) bool {
+		edge, _ := EdgeBetween(from, to)
+		return isWalkable(to) && !isEdgeBlocked(edge)
+	}
+}
+
+func (p *Pathfinder) search(ctx context.Context, start, goal Hex, canStep func(from, to Hex) bool, cost CostFunc, minCost int, limits SearchLimits) PathResult {
+	minCost = max(minCost, 0)
+	p.reset()
+
+	startSlot, ok := p.slot(start)
+	if !ok {
+		return PathResult{Status: PathStartBlocked}
+	}
+	_, goalInside := p.slot(goal)
+	if !goalInside && !limits.Closest {
+		return PathResult{Status: PathGoalBlocked}
+	}
+	p.visit(startSlot, 0, -1)
+	p.push(openNode{slot: startSlot})
+
+	status := PathUnreachable
+	if !goalInside {
+		status = PathGoalBlocked
+	}
+	closest, closestDistance := startSlot, HexDistance(start, goal)
+	expanded := 0
+	for len(p.open) > 0 {
+		s := p.pop().slot
+		if p.closed[s] == p.generation {
+			continue
+		}
+		current := p.hexes[s]
+		if current == goal {
+			return PathResult{Path: p.reconstructPath(s), Cost: p.g[s], Status: PathFound, Expanded: expanded}
+		}
+
+		if limits.MaxNodes > 0 && expanded >= limits.MaxNodes {
+			if status == PathUnreachable {
+				status = PathBudgetExceeded
+			}
+			break
+		}
+		if expanded%cancelCheckInterval == 0 && ctx.Err() != nil {
+			status = PathCancelled
+			break
+		}
+		p.closed[s] = p.generation
+		expanded++
+		if d := HexDistance(current, goal); d < closestDistance || d == closestDistance && p.g[s] < p.g[closest] {
+			closest, closestDistance = s, d
+		}
+
+		for _, dir := range directions {
+			neighbor := current.Add(dir)
+			n, ok := p.slot(neighbor)
+			if !ok || p.closed[n] == p.generation || !canStep(current, neighbor) {
+				continue
+			}
+
+			g := p.g[s] + cost(current, neighbor)
+			if limits.MaxCost > 0 && g > limits.MaxCost {
+				// Only a goal seen beyond the cap is known to be out of
+				// budget; other pruned hexes may lead nowhere.
+				if neighbor == goal && status == PathUnreachable {
+					status = PathBudgetExceeded
+				}
+				continue
+			}
+			if p.seen[n] != p.generation || g < p.g[n] {
+				p.visit(n, g, s)
+				h := int(HexDistance(neighbor, goal)) * minCost
+				node := openNode{f: g + h, h: h, slot: n}
+				if p.StraightLines {
+					node.tie = deviation(start, goal, neighbor)
+				}
+				p.push(node)
+			}
+		}
+	}
+
+	result := PathResult{Status: status, Expanded: expanded}
+	if limits.Closest {
+		result.Path, result.Cost = p.reconstructPath(closest), p.g[closest]
+	}
+	return result
+}
+
+// reset starts a new search generation, reusing every buffer.
+func (p *Pathfinder) reset() {
+	p.open = p.open[:0]
+	p.generation++
+	if p.generation == 0 {
+		clear(p.seen)
+		clear(p.closed)
+		p.generation = 1
+	}
+	if !p.dense {
+		clear(p.index)
+		p.hexes = p.hexes[:0]
+		p.g = p.g[:0]
+		p.parent = p.parent[:0]
+		p.seen = p.seen[:0]
+		p.closed = p.closed[:0]
+	}
+}
+
+// slot returns the buffer index for h, creating one on unbounded maps. It
+// returns false for hexes outside a bounded Pathfinder.
+func (p *Pathfinder) slot(h Hex) (int32, bool) {
+	if p.dense {
+		q, r := h.Q-p.minQ, h.R-p.minR
+		if q < 0 || r < 0 || q >= p.width || r >= p.height {
+			return 0, false
+		}
+		return int32(q*p.height + r), true
+	}
+
+	if s, ok := p.index[h]; ok {
+		return s, true
+	}
+	s := int32(len(p.hexes))
+	p.index[h] = s
+	p.hexes = append(p.hexes, h)
+	p.g = append(p.g, 0)
+	p.parent = append(p.parent, -1)
+	p.seen = append(p.seen, 0)
+	p.closed = append(p.closed, 0)
+	return s, true
+}
+
+func (p *Pathfinder) visit(s int32, g int, parent int32) {
+	p.seen[s] = p.generation
+	p.g[s] = g
+	p.parent[s] = parent
+}
+
+func (p *Pathfinder) reconstructPath(s int32) []Hex {
+	p.path = p.path[:0]
+	for ; s >= 0; s = p.parent[s] {
+		p.path = append(p.path, p.hexes[s])
+	}
+	slices.Reverse(p.path)
+	return p.path
+}
+
+func (p *Pathfinder) push(node openNode) {
+	p.open = append(p.open, node)
+	i := len(p.open) - 1
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !node.less(p.open[parent]) {
+			break
+		}
+		p.open[i] = p.open[parent]
+		i = parent
+	}
+	p.open[i] = node
+}
+
+func (p *Pathfinder) pop() openNode {
+	top := p.open[0]
+	last := p.open[len(p.open)-1]
+	p.open = p.open[:len(p.open)-1]
+	if len(p.open) == 0 {
+		return top
+	}
+
+	i := 0
+	for {
+		child := 2*i + 1
+		if child >= len(p.open) {
+			break
+		}
+		if right := child + 1; right < len(p.open) && p.open[right].less(p.open[child]) {
+			child = right
+		}
+		if !p.open[child].less(last) {
+			break
+		}
+		p.open[i] = p.open[child]
+		i = child
+	}
+	p.open[i] = last
+	return top
+}
+
+// deviation measures how far h strays from the straight line between start
+// and goal, as the size of a cross product on a plane where the hex axes are
+// 60° apart. Only the ordering matters, so it skips the constant factors.
+func deviation(start, goal, h Hex) int64 {
+	ax, ay := 2*(h.Q-goal.Q)+(h.R-goal.R), h.R-goal.R
+	bx, by := 2*(start.Q-goal.Q)+(start.R-goal.R), start.R-goal.R
+	return abs(ax*by - bx*ay)
+}
@@ -0,0 +1,91 @@
+package hex
+
+// Ring returns the hexes exactly radius steps from center. Every ring starts
+// from the same corner and walks around in the same direction, so index i of
+// one ring lines up angularly with the proportional index of the next.
+func Ring(center Hex, radius int64) []Hex {
+	if radius <= 0 {
+		return []Hex{center}
+	}
+
+	results := make([]Hex, 0, 6*radius)
+	h := Hex{Q: center.Q + directions[4].Q*radius, R: center.R + directions[4].R*radius}
+	for _, dir := range directions {
+		for j := int64(0); j < radius; j++ {
+			results = append(results, h)
+			h = Hex{Q: h.Q + dir.Q, R: h.R + dir.R}
+		}
+	}
+	return results
+}
+
+// Spiral returns the center followed by every ring out to radius.
+func Spiral(center Hex, radius int64) []Hex {
+	results := make([]Hex, 0, rangeSize(radius))
+	for r := int64(0); r <= radius; r++ {
+		results = append(results, Ring(center, r)...)
+	}
+	return results
+}
+
+// Range returns every hex within n steps of center, ordered by q then r.
+func Range(center Hex, n int64) []Hex {
+	results := make([]Hex, 0, rangeSize(n))
+	for q := -n; q <= n; q++ {
+		for r := max(-n, -q-n); r <= min(n, -q+n); r++ {
+			results = append(results, Hex{Q: center.Q + q, R: center.R + r})
+		}
+	}
+	return results
+}
+
+// IntersectRanges returns the hexes within radiusA of a and within radiusB of
+// b, without enumerating either range in full.
+func IntersectRanges(a Hex, radiusA int64, b Hex, radiusB int64) []Hex {
+	// In cube coordinates a range is the box |dq|,|dr|,|ds| <= n, so the
+	// intersection of two ranges is the intersection of two boxes.
+	aS, bS := -a.Q-a.R, -b.Q-b.R
+	minQ, maxQ := max(a.Q-radiusA, b.Q-radiusB), min(a.Q+radiusA, b.Q+radiusB)
+	minR, maxR := max(a.R-radiusA, b.R-radiusB), min(a.R+radiusA, b.R+radiusB)
+	minS, maxS := max(aS-radiusA, bS-radiusB), min(aS+radiusA, bS+radiusB)
+
+	results := []Hex{}
+	for q := minQ; q <= maxQ; q++ {
+		for r := max(minR, -q-maxS); r <= min(maxR, -q-minS); r++ {
+			results = append(results, Hex{Q: q, R: r})
+		}
+	}
+	return results
+}
+
+// RangeWithObstacles returns the hexes that can be reached from center in at
+// most n steps without passing through a blocking hex, in order of distance.
+func RangeWithObstacles(center Hex, n int64, isBlocking func(Hex) bool) []Hex {
+	visited := map[Hex]bool{center: true}
+	results := []Hex{center}
+	fringe := []Hex{center}
+
+	for step := int64(1); step <= n && len(fringe) > 0; step++ {
+		next := []Hex{}
+		for _, h := range fringe {
+			for _, neighbor := range GetNeighbors(h) {
+				if visited[neighbor] || isBlocking(neighbor) {
+					continue
+				}
+				visited[neighbor] = true
+				results = append(results, neighbor)
+				next = append(next, neighbor)
+			}
+		}
+		fringe = next
+	}
+	return results
+}
+
+// rangeSize is the number of hexes within n steps of a hex.
+func rangeSize(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return 3*n*(n+1) + 1
+}
@@ -0,0 +1,136 @@
+package hex
+
+import (
+	"container/heap"
+	"slices"
+)
+
+// Occupant describes who, if anyone, is standing on a hex.
+type Occupant int
+
+const (
+	Unoccupied Occupant = iota
+	// Ally hexes can be moved through but not ended on.
+	Ally
+	// Enemy hexes block movement entirely.
+	Enemy
+)
+
+// ReachNode records how a hex was reached during a movement-range search.
+type ReachNode struct {
+	Cost    int
+	From    Hex
+	CanStop bool
+}
+
+// Reach holds every hex reachable from Origin within a movement budget.
+type Reach struct {
+	Origin Hex
+	Nodes  map[Hex]ReachNode
+}
+
+// Reachable runs a Dijkstra flood fill from origin and returns every hex that
+// can be reached for at most budget movement, using the same isWalkable and
+// cost callbacks as FindWeightedPath. occupant may be nil when no units need
+// to be taken into account.
+func Reachable(origin Hex, budget int, isWalkable func(Hex) bool, cost CostFunc, occupant func(Hex) Occupant) *Reach {
+	return reachable(origin, budget, isWalkable, cost, occupant, func(Hex) bool { return false })
+}
+
+// reachable is the flood fill behind Reachable. Hexes for which stops
+// returns true can be moved onto but not through.
+func reachable(origin Hex, budget int, isWalkable func(Hex) bool, cost CostFunc, occupant func(Hex) Occupant, stops func(Hex) bool) *Reach {
+	if occupant == nil {
+		occupant = func(Hex) Occupant { return Unoccupied }
+	}
+
+	reach := &Reach{
+		Origin: origin,
+		Nodes:  map[Hex]ReachNode{origin: {Cost: 0, From: origin, CanStop: true}},
+	}
+	closedSet := make(map[Hex]bool)
+	openSet := &PriorityQueue{}
+	heap.Init(openSet)
+	heap.Push(openSet, &PathNode{hex: origin, fScore: 0})
+
+	for openSet.Len() > 0 {
+		current := heap.Pop(openSet).(*PathNode).hex
+		if closedSet[current] {
+			continue
+		}
+		closedSet[current] = true
+		if current != origin && stops(current) {
+			continue
+		}
+
+		for _, neighbor := range GetNeighbors(current) {
+			if closedSet[neighbor] || !isWalkable(neighbor) {
+				continue
+			}
+			who := occupant(neighbor)
+			if who == Enemy {
+				continue
+			}
+
+			tentativeCost := reach.Nodes[current].Cost + cost(current, neighbor)
+			if tentativeCost > budget {
+				continue
+			}
+			if node, exists := reach.Nodes[neighbor]; exists && node.Cost <= tentativeCost {
+				continue
+			}
+
+			reach.Nodes[neighbor] = ReachNode{
+				Cost:    tentativeCost,
+				From:    current,
+				CanStop: who != Ally,
+			}
+			heap.Push(openSet, &PathNode{hex: neighbor, fScore: tentativeCost})
+		}
+	}
+
+	return reach
+}
+
+// Destinations returns the hexes a unit can end its move on, cheapest first.
+// The origin is included.
+func (r *Reach) Destinations() []Hex {
+	hexes := make([]Hex, 0, len(r.Nodes))
+	for h, node := range r.Nodes {
+		if node.CanStop {
+			hexes = append(hexes, h)
+		}
+	}
+	slices.SortFunc(hexes, func(a, b Hex) int {
+		if d := r.Nodes[a].Cost - r.Nodes[b].Cost; d != 0 {
+			return d
+		}
+		if a.Q != b.Q {
+			return int(a.Q - b.Q)
+		}
+		return int(a.R - b.R)
+	})
+	return hexes
+}
+
+// CostTo returns the movement cost to reach h and whether it is in range.
+func (r *Reach) CostTo(h Hex) (int, bool) {
+	node, ok := r.Nodes[h]
+	return node.Cost, ok
+}
+
+// PathTo returns the cheapest path from the origin to h, or nil if h is out
+// of range. The path may end on an ally; check CanStop before committing.
+func (r *Reach) PathTo(h Hex) []Hex {
+	if _, ok := r.Nodes[h]; !ok {
+		return nil
+	}
+
+	path := []Hex{h}
+	for h != r.Origin {
+		h = r.Nodes[h].From
+		path = append(path, h)
+	}
+	slices.Reverse(path)
+	return path
+}
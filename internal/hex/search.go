@@ -0,0 +1,77 @@
+package hex
+
+import "context"
+
+// PathStatus says how a search ended.
+type PathStatus int
+
+const (
+	PathFound PathStatus = iota
+	// PathUnreachable means every hex reachable from the start, within
+	// SearchLimits.MaxCost if set, was searched without finding the goal.
+	PathUnreachable
+	// PathBudgetExceeded means MaxNodes stopped the search with hexes left
+	// to explore, so the goal may still be reachable, or that the goal was
+	// reached but only for more than MaxCost.
+	PathBudgetExceeded
+	PathStartBlocked
+	PathGoalBlocked
+	PathCancelled
+)
+
+func (s PathStatus) String() string {
+	switch s {
+	case PathFound:
+		return "found"
+	case PathUnreachable:
+		return "unreachable"
+	case PathBudgetExceeded:
+		return "budget exceeded"
+	case PathStartBlocked:
+		return "start blocked"
+	case PathGoalBlocked:
+		return "goal blocked"
+	default:
+		return "cancelled"
+	}
+}
+
+// SearchLimits caps how much work a search may do. Zero fields are
+// unlimited, so the zero value behaves like FindWeightedPath.
+type SearchLimits struct {
+	// MaxNodes is how many hexes may be expanded.
+	MaxNodes int
+	// MaxCost is the most a path may cost; hexes beyond it are not explored.
+	MaxCost int
+	// Closest asks for the path to the explored hex nearest the goal when
+	// the goal itself is not reached, so a unit can at least head that way.
+	Closest bool
+}
+
+// PathResult is the outcome of a search. Path and Cost describe the route
+// to the goal when Status is PathFound; otherwise they describe the route to
+// the closest hex when SearchLimits.Closest was set, and are empty if not.
+type PathResult struct {
+	Path     []Hex
+	Cost     int
+	Status   PathStatus
+	Expanded int
+}
+
+func (r PathResult) Found() bool {
+	return r.Status == PathFound
+}
+
+// Search is FindWeightedPath with limits, cancellation and a reason for
+// failure. Where FindPath always gives up after MaxPathNodes hexes, Search
+// takes the caller's limits and a deadline on ctx, and says why it stopped.
+// A blocked start or goal is reported without searching.
+func Search(ctx context.Context, start, goal Hex, isWalkable func(Hex) bool, cost CostFunc, minCost int, limits SearchLimits) PathResult {
+	return NewPathfinder().Search(ctx, start, goal, isWalkable, cost, minCost, limits)
+}
+
+// SearchWithEdges is Search for maps with walls and closed doors on hex
+// borders, as in FindPathWithEdges.
+func SearchWithEdges(ctx context.Context, start, goal Hex, isWalkable func(Hex) bool, isEdgeBlocked func(Edge) bool, cost CostFunc, minCost int, limits SearchLimits) PathResult {
+	return NewPathfinder().SearchWithEdges(ctx, start, goal, isWalkable, isEdgeBlocked, cost, minCost, limits)
+}
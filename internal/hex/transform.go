@@ -0,0 +1,107 @@
+package hex
+
+// Axis is one of the three lines through a hex that reflections mirror
+// across. AxisQ keeps q fixed and swaps r and s, and so on.
+type Axis int
+
+const (
+	AxisQ Axis = iota
+	AxisR
+	AxisS
+)
+
+func (h Hex) Add(v Hex) Hex {
+	return Hex{Q: h.Q + v.Q, R: h.R + v.R}
+}
+
+func (h Hex) Sub(v Hex) Hex {
+	return Hex{Q: h.Q - v.Q, R: h.R - v.R}
+}
+
+// Scale multiplies the hex's distance from (0,0) by factor.
+func (h Hex) Scale(factor int64) Hex {
+	return Hex{Q: h.Q * factor, R: h.R * factor}
+}
+
+// RotateAround turns h around center by steps multiples of 60°,
+// counter-clockwise for positive steps, the same way Direction.Rotate does.
+func (h Hex) RotateAround(center Hex, steps int) Hex {
+	v := h.Sub(center)
+	q, r, s := v.Q, v.R, -v.Q-v.R
+	for range Direction(steps).normalize() {
+		q, r, s = -s, -q, -r
+	}
+	return center.Add(Hex{Q: q, R: r})
+}
+
+// ReflectAround mirrors h across the given axis through center.
+func (h Hex) ReflectAround(center Hex, axis Axis) Hex {
+	v := h.Sub(center)
+	q, r, s := v.Q, v.R, -v.Q-v.R
+	switch axis {
+	case AxisQ:
+		r, s = s, r
+	case AxisR:
+		q, s = s, q
+	default:
+		q, r = r, q
+	}
+	return center.Add(Hex{Q: q, R: r})
+}
+
+// Transform moves hexes onto hexes. Author a pattern once around (0,0) and
+// stamp it anywhere by composing rotations, reflections and translations.
+type Transform func(Hex) Hex
+
+func Translation(by Hex) Transform {
+	return func(h Hex) Hex { return h.Add(by) }
+}
+
+func Rotation(center Hex, steps int) Transform {
+	return func(h Hex) Hex { return h.RotateAround(center, steps) }
+}
+
+func Reflection(center Hex, axis Axis) Transform {
+	return func(h Hex) Hex { return h.ReflectAround(center, axis) }
+}
+
+// Scaling spreads hexes away from center by factor, leaving gaps between
+// them; it is meant for spacing out patterns, not for growing shapes.
+func Scaling(center Hex, factor int64) Transform {
+	return func(h Hex) Hex { return center.Add(h.Sub(center).Scale(factor)) }
+}
+
+// Then returns a transform that applies t and then next.
+func (t Transform) Then(next Transform) Transform {
+	return func(h Hex) Hex { return next(t(h)) }
+}
+
+// Apply transforms every hex into a new slice, keeping the order.
+func (t Transform) Apply(hexes []Hex) []Hex {
+	results := make([]Hex, len(hexes))
+	for i, h := range hexes {
+		results[i] = t(h)
+	}
+	return results
+}
+
+// Symmetrize adds the copies of hexes rotated around center that give the
+// pattern order-fold rotational symmetry. order must divide 6; other values
+// are treated as 1. Duplicates are dropped and the originals come first.
+func Symmetrize(hexes []Hex, center Hex, order int) []Hex {
+	if order <= 0 || 6%order != 0 {
+		order = 1
+	}
+	seen := make(map[Hex]bool, len(hexes)*order)
+	results := make([]Hex, 0, len(hexes)*order)
+	for i := range order {
+		for _, h := range hexes {
+			rotated := h.RotateAround(center, i*6/order)
+			if !seen[rotated] {
+				seen[rotated] = true
+				results = append(results, rotated)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,108 @@
+package hex
+
+// ZoneOfControl describes the hexes hostile units threaten: every hex next
+// to an enemy, as reported by Occupant. Walking into a controlled hex can end
+// the move or cost extra, and walking out of an enemy's reach provokes an
+// opportunity attack from it.
+type ZoneOfControl struct {
+	Occupant func(Hex) Occupant
+	// Stop ends movement on the first controlled hex entered. A unit that
+	// starts its move in a zone may still walk out of it.
+	Stop bool
+	// Penalty is added to the cost of entering a controlled hex.
+	Penalty int
+}
+
+// OpportunityAttack is an attack provoked by leaving the reach of the enemy
+// on Attacker while stepping off Hex.
+type OpportunityAttack struct {
+	Hex      Hex
+	Attacker Hex
+}
+
+// ZonePath is a path found under zone of control, with every opportunity
+// attack walking it would provoke, in the order they happen.
+type ZonePath struct {
+	Path    []Hex
+	Cost    int
+	Attacks []OpportunityAttack
+}
+
+// Controlled reports whether h is next to an enemy.
+func (z ZoneOfControl) Controlled(h Hex) bool {
+	for _, dir := range directions {
+		if z.Occupant(h.Add(dir)) == Enemy {
+			return true
+		}
+	}
+	return false
+}
+
+// Attackers returns the enemy hexes next to h.
+func (z ZoneOfControl) Attackers(h Hex) []Hex {
+	attackers := []Hex{}
+	for _, dir := range directions {
+		if n := h.Add(dir); z.Occupant(n) == Enemy {
+			attackers = append(attackers, n)
+		}
+	}
+	return attackers
+}
+
+// OpportunityAttacks lists the attacks provoked along path: one for every
+// enemy next to a hex the path steps off that is no longer next to the hex
+// it steps onto.
+func (z ZoneOfControl) OpportunityAttacks(path []Hex) []OpportunityAttack {
+	attacks := []OpportunityAttack{}
+	for i := 1; i < len(path); i++ {
+		for _, attacker := range z.Attackers(path[i-1]) {
+			if HexDistance(attacker, path[i]) > 1 {
+				attacks = append(attacks, OpportunityAttack{Hex: path[i-1], Attacker: attacker})
+			}
+		}
+	}
+	return attacks
+}
+
+// cost adds the zone's penalty to entering controlled hexes.
+func (z ZoneOfControl) cost(cost CostFunc) CostFunc {
+	if z.Penalty == 0 {
+		return cost
+	}
+	return func(from, to Hex) int {
+		if z.Controlled(to) {
+			return cost(from, to) + z.Penalty
+		}
+		return cost(from, to)
+	}
+}
+
+// FindZonePath is FindWeightedPath for a unit moving among enemies. Enemy
+// hexes are never entered, the zone's Stop and Penalty rules are applied,
+// and the result lists the opportunity attacks the path provokes so the
+// player can be warned before committing the move. Path is nil when the
+// goal cannot be reached.
+func FindZonePath(start, goal Hex, isWalkable func(Hex) bool, cost CostFunc, minCost int, zone ZoneOfControl) ZonePath {
+	canStep := func(from, to Hex) bool {
+		if !isWalkable(to) || zone.Occupant(to) == Enemy {
+			return false
+		}
+		return !zone.Stop || from == start || !zone.Controlled(from)
+	}
+	path, total := findPath(start, goal, canStep, zone.cost(cost), minCost)
+	if path == nil {
+		return ZonePath{}
+	}
+	return ZonePath{Path: path, Cost: total, Attacks: zone.OpportunityAttacks(path)}
+}
+
+// ReachableInZone is Reachable for a unit moving among enemies, with the
+// zone's Stop and Penalty rules applied. Check the path to a destination
+// with OpportunityAttacks before committing to it.
+func ReachableInZone(origin Hex, budget int, isWalkable func(Hex) bool, cost CostFunc, zone ZoneOfControl) *Reach {
+	stops := func(Hex) bool { return false }
+	if zone.Stop {
+		stops = zone.Controlled
+	}
+	return reachable(origin, budget, isWalkable, zone.cost(cost), zone.Occupant, stops)
+}